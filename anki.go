@@ -0,0 +1,684 @@
+package leaf
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// AnkiImport describes decks converted from an Anki package.
+type AnkiImport struct {
+	Algorithm SRS
+	// Files are paths of written deck files.
+	Files []string
+	Cards int
+	Media int
+	// Reviews are converted entries of Anki review log.
+	Reviews []Review
+	// DroppedTags are sorted Anki tags of imported notes that can't
+	// be used in headlines.
+	DroppedTags []string
+}
+
+type ankiModel struct {
+	Name  string            `json:"name"`
+	Type  int               `json:"type"`
+	Tmpls []json.RawMessage `json:"tmpls"`
+}
+
+type ankiNote struct {
+	id     int64
+	model  ankiModel
+	tags   []string
+	fields []string
+	// dropped are tags that can't be converted into headline tags.
+	dropped []string
+	// ords are template ordinals of note cards.
+	ords map[int64]bool
+	deck int64
+	ord  int64
+	// imported is set for notes written into a deck file.
+	imported bool
+}
+
+type ankiDeck struct {
+	// path of a deck file relative to the root.
+	path  string
+	name  string
+	title string
+	notes []*ankiNote
+	media map[string]bool
+}
+
+var (
+	ankiBreakRegexp = regexp.MustCompile(`(?i)<br\s*/?>|</(?:div|p|li|tr)>`)
+	ankiImageRegexp = regexp.MustCompile(`(?i)<img[^>]*\ssrc\s*=\s*["']?([^"'\s>]+)["']?[^>]*>`)
+	ankiBoldRegexp  = regexp.MustCompile(`(?i)</?(?:b|strong)>`)
+	ankiEmRegexp    = regexp.MustCompile(`(?i)</?(?:i|em)>`)
+	ankiTagRegexp   = regexp.MustCompile(`<[^>]*>`)
+	ankiSoundRegexp = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	ankiLinkRegexp  = regexp.MustCompile(`\[\[[^\]]*\]\]`)
+)
+
+// ImportAnki converts notes of an Anki package (.apkg) into org decks
+// written into a deck root dir. Each Anki deck is written into a
+// separate file, nested decks (e.g. "Japanese::Verbs") are placed
+// into folders, so that deck names match. Images are copied next to
+// the deck files and linked using /images/ paths relative to the
+// root.
+//
+// First field of a note is used as a question and other fields are
+// used as sides. Notes with a second card template use REVERSE
+// property, cloze notes keep their deletions. Other card templates
+// are not imported. Only packages with legacy collection format
+// (collection.anki2 or collection.anki21) are supported. Existing
+// files are never overwritten, nothing is written if any of the
+// files already exists or the package can't be read.
+func ImportAnki(filename, dir string, srs SRS) (*AnkiImport, error) {
+	if _, err := NewStats(srs, SystemClock, nil); err != nil {
+		return nil, err
+	}
+
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("anki: %s", err)
+	}
+	defer archive.Close()
+
+	entries := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		entries[f.Name] = f
+	}
+
+	collection := entries["collection.anki21"]
+	if collection == nil {
+		collection = entries["collection.anki2"]
+	}
+	if collection == nil {
+		return nil, fmt.Errorf("anki: unsupported package, export it with \"Support older Anki versions\" option")
+	}
+
+	data, err := readZipFile(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openSQLite(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decks, cardIDs, err := readAnkiCollection(db)
+	if err != nil {
+		return nil, err
+	}
+
+	media := make(map[string]string)
+	if f := entries["media"]; f != nil {
+		content, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make(map[string]string)
+		if err := json.Unmarshal(content, &names); err != nil {
+			return nil, fmt.Errorf("anki: unsupported media list: %s", err)
+		}
+		for entry, name := range names {
+			media[filepath.Base(name)] = entry
+		}
+	}
+
+	result := &AnkiImport{Algorithm: srs}
+	files := make([]ankiFile, 0)
+	copied := make(map[string]bool)
+	dropped := make(map[string]bool)
+	for _, deck := range decks {
+		content, cards := deck.org(srs)
+		if cards == 0 {
+			continue
+		}
+
+		for _, note := range deck.notes {
+			for _, tag := range note.dropped {
+				if note.imported && !dropped[tag] {
+					dropped[tag] = true
+					result.DroppedTags = append(result.DroppedTags, tag)
+				}
+			}
+		}
+
+		file := filepath.Join(dir, filepath.FromSlash(deck.path))
+		files = append(files, ankiFile{path: file, data: []byte(content)})
+		result.Files = append(result.Files, file)
+		result.Cards += cards
+
+		names := make([]string, 0, len(deck.media))
+		for name := range deck.media {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			f, target := entries[media[name]], filepath.Join(filepath.Dir(file), name)
+			if f == nil || copied[target] {
+				continue
+			}
+
+			files = append(files, ankiFile{path: target, entry: f})
+			copied[target] = true
+			result.Media++
+		}
+	}
+
+	sort.Strings(result.DroppedTags)
+
+	if result.Reviews, err = readAnkiRevlog(db, cardIDs); err != nil {
+		return nil, err
+	}
+
+	// files are written last, so that failed imports can be retried
+	if err := writeAnkiFiles(files); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Save logs imported reviews and saves card stats replayed through
+// the algorithm of imported decks.
+func (ai *AnkiImport) Save(db StatsStore, clock Clock) error {
+	decks := make(map[string][]Review)
+	for idx := range ai.Reviews {
+		review := ai.Reviews[idx]
+		if err := db.LogReview(&review); err != nil {
+			return err
+		}
+		decks[review.Deck] = append(decks[review.Deck], review)
+	}
+
+	for deck, reviews := range decks {
+		replayed, err := ReplayReviews(reviews, ai.Algorithm, clock, nil)
+		if err != nil {
+			return err
+		}
+
+		for card, stats := range replayed {
+			if err := db.SaveStats(deck, card, stats); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ankiCard references a card converted from an Anki card.
+type ankiCard struct {
+	deck string
+	id   string
+	note *ankiNote
+}
+
+// readAnkiCollection returns decks with notes of a collection
+// along with converted card references keyed by Anki card ID.
+func readAnkiCollection(db *sqliteDB) ([]*ankiDeck, map[int64]ankiCard, error) {
+	col, err := db.table("col")
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(col) == 0 {
+		return nil, nil, fmt.Errorf("anki: empty collection")
+	}
+
+	// col columns: id, crt, mod, scm, ver, dty, usn, ls, conf, models, decks, dconf, tags
+	models := make(map[string]ankiModel)
+	if err := json.Unmarshal([]byte(col[0].text(9)), &models); err != nil {
+		return nil, nil, fmt.Errorf("anki: invalid note types: %s", err)
+	}
+
+	deckNames := make(map[string]struct {
+		Name string `json:"name"`
+	})
+	if err := json.Unmarshal([]byte(col[0].text(10)), &deckNames); err != nil {
+		return nil, nil, fmt.Errorf("anki: invalid decks: %s", err)
+	}
+
+	noteRows, err := db.table("notes")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// notes columns: id, guid, mid, mod, usn, tags, flds, ...
+	notes := make(map[int64]*ankiNote, len(noteRows))
+	for _, row := range noteRows {
+		model, ok := models[strconv.FormatInt(row.int(2), 10)]
+		if !ok {
+			continue
+		}
+
+		tags, dropped := ankiTags(row.text(5))
+		notes[row.int(0)] = &ankiNote{
+			id:      row.int(0),
+			model:   model,
+			tags:    tags,
+			dropped: dropped,
+			fields:  strings.Split(row.text(6), "\x1f"),
+			ords:    make(map[int64]bool),
+			deck:    -1,
+		}
+	}
+
+	cardRows, err := db.table("cards")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// cards columns: id, nid, did, ord, ...
+	for _, row := range cardRows {
+		note := notes[row.int(1)]
+		if note == nil {
+			continue
+		}
+
+		note.ords[row.int(3)] = true
+		if note.deck == -1 || row.int(3) < note.ord {
+			note.deck, note.ord = row.int(2), row.int(3)
+		}
+	}
+
+	byID := make(map[int64]*ankiDeck)
+	usedPaths := make(map[string]bool)
+	deckIDs := make([]int64, 0, len(deckNames))
+	for id := range deckNames {
+		if did, err := strconv.ParseInt(id, 10, 64); err == nil {
+			deckIDs = append(deckIDs, did)
+		}
+	}
+	sort.Slice(deckIDs, func(i, j int) bool {
+		return deckNames[strconv.FormatInt(deckIDs[i], 10)].Name < deckNames[strconv.FormatInt(deckIDs[j], 10)].Name
+	})
+
+	decks := make([]*ankiDeck, 0, len(deckIDs))
+	for _, did := range deckIDs {
+		deck := newAnkiDeck(deckNames[strconv.FormatInt(did, 10)].Name, usedPaths)
+		byID[did] = deck
+		decks = append(decks, deck)
+	}
+
+	noteIDs := make([]int64, 0, len(notes))
+	for id := range notes {
+		noteIDs = append(noteIDs, id)
+	}
+	sort.Slice(noteIDs, func(i, j int) bool { return noteIDs[i] < noteIDs[j] })
+
+	for _, id := range noteIDs {
+		if deck := byID[notes[id].deck]; deck != nil {
+			deck.notes = append(deck.notes, notes[id])
+		}
+	}
+
+	cardIDs := make(map[int64]ankiCard, len(cardRows))
+	for _, row := range cardRows {
+		note := notes[row.int(1)]
+		if note == nil || byID[note.deck] == nil {
+			continue
+		}
+
+		if id := note.cardID(row.int(3)); id != "" {
+			cardIDs[row.int(0)] = ankiCard{byID[note.deck].name, id, note}
+		}
+	}
+
+	return decks, cardIDs, nil
+}
+
+// newAnkiDeck returns a deck for a "::" separated Anki deck name.
+// Deck file path is unique among used paths.
+func newAnkiDeck(name string, usedPaths map[string]bool) *ankiDeck {
+	parts := strings.Split(name, "::")
+	dirs := make([]string, 0, len(parts)-1)
+	for _, part := range parts[:len(parts)-1] {
+		dirs = append(dirs, ankiFileName(part))
+	}
+
+	title := strings.TrimSpace(parts[len(parts)-1])
+	base := strings.Join(append(dirs, ankiFileName(title)), "/")
+	path := base + ".org"
+	for idx := 2; usedPaths[strings.ToLower(path)]; idx++ {
+		path = base + "_" + strconv.Itoa(idx) + ".org"
+	}
+	usedPaths[strings.ToLower(path)] = true
+
+	deck := &ankiDeck{path: path, title: title, name: title, media: make(map[string]bool)}
+	if len(dirs) > 0 {
+		deck.name = strings.Join(dirs, "/") + "/" + title
+	}
+
+	return deck
+}
+
+// cardID returns ID of a converted card for a template ordinal,
+// empty value is returned for templates that are not imported.
+func (note *ankiNote) cardID(ord int64) string {
+	id := "anki-" + strconv.FormatInt(note.id, 10)
+	if note.model.Type == 1 {
+		return clozeCardID(id, int(ord)+1)
+	}
+
+	switch ord {
+	case 0:
+		return id
+	case 1:
+		return reversedCardID(id)
+	}
+
+	return ""
+}
+
+// org returns content of a deck file along with amount of cards.
+func (deck *ankiDeck) org(srs SRS) (string, int) {
+	var sb strings.Builder
+	sb.WriteString("* " + deck.title + "\n")
+	sb.WriteString(":PROPERTIES:\n:ALGORITHM: " + string(srs) + "\n:RATER: self\n:END:\n")
+
+	prefix := "/images/"
+	if dir := path.Dir(deck.path); dir != "." {
+		prefix += dir + "/"
+	}
+
+	cards := 0
+	for _, note := range deck.notes {
+		fields := make([]string, len(note.fields))
+		for idx, field := range note.fields {
+			fields[idx] = ankiToOrg(field, prefix, deck.media)
+		}
+
+		title, body := strings.Replace(fields[0], "\n", " ", -1), ""
+		var props [][2]string
+		if note.model.Type == 1 {
+			clozes := parseClozes(fields[0])
+			if clozes == nil {
+				continue
+			}
+
+			title = clozeRegexp.ReplaceAllString(title, "$2")
+			title = strings.TrimSpace(ankiLinkRegexp.ReplaceAllString(title, ""))
+			if utf8.RuneCountInString(title) > 50 {
+				title = string([]rune(title)[:50]) + "…"
+			}
+			body = fields[0]
+			cards += len(clozes)
+		} else {
+			var sides []string
+			for _, field := range fields[1:] {
+				if field != "" {
+					sides = append(sides, field)
+				}
+			}
+			if title == "" || len(sides) == 0 {
+				continue
+			}
+
+			body = strings.Join(sides, "\n")
+			cards++
+			if note.ords[1] && len(note.model.Tmpls) > 1 {
+				props = append(props, [2]string{"REVERSE", "t"})
+				cards++
+			}
+		}
+
+		if len(note.tags) > 0 {
+			title += " :" + strings.Join(note.tags, ":") + ":"
+		}
+
+		note.imported = true
+		sb.WriteString("** " + title + "\n:PROPERTIES:\n")
+		sb.WriteString(":ID: anki-" + strconv.FormatInt(note.id, 10) + "\n")
+		for _, prop := range props {
+			sb.WriteString(":" + prop[0] + ": " + prop[1] + "\n")
+		}
+		sb.WriteString(":END:\n" + body + "\n")
+	}
+
+	return sb.String(), cards
+}
+
+// readAnkiRevlog converts review log of imported cards. Anki answer
+// buttons are converted to review scores, "again" answers are
+// counted as mistakes of the following successful review same as in
+// review sessions. Manual rescheduling entries are skipped.
+func readAnkiRevlog(db *sqliteDB, cards map[int64]ankiCard) ([]Review, error) {
+	rows, err := db.table("revlog")
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].int(0) < rows[j].int(0) })
+
+	// revlog columns: id, cid, usn, ease, ivl, lastIvl, factor, time, type
+	rater, table := &harshRater{make(map[string]float64)}, TableRater()
+	reviews := make([]Review, 0, len(rows))
+	for _, row := range rows {
+		card, ok := cards[row.int(1)]
+		ease := row.int(3)
+		if !ok || !card.note.imported || ease < 1 || ease > 4 || row.int(8) == 4 {
+			continue
+		}
+
+		key := card.deck + "/" + card.id
+		score := ReviewScore(ease - 1)
+		rating := table.Rate(key, score)
+		if score == ReviewScoreAgain || rater.mistakes[key] > 0 {
+			rating = rater.Rate(key, score)
+		}
+		if score != ReviewScoreAgain {
+			delete(rater.mistakes, key)
+		}
+
+		reviewedAt := time.Unix(0, row.int(0)*int64(time.Millisecond)).UTC()
+		reviews = append(reviews, Review{
+			Deck:             card.deck,
+			Card:             card.id,
+			SessionStartedAt: reviewedAt,
+			ReviewedAt:       reviewedAt,
+			Score:            score,
+			Rating:           rating,
+			Elapsed:          time.Duration(row.int(7)) * time.Millisecond,
+			PrevInterval:     ankiInterval(row.int(5)),
+			Interval:         ankiInterval(row.int(4)),
+		})
+	}
+
+	return reviews, nil
+}
+
+// ankiInterval converts Anki interval to days, negative intervals
+// are in seconds.
+func ankiInterval(ivl int64) float64 {
+	if ivl < 0 {
+		return float64(-ivl) / 86400
+	}
+
+	return float64(ivl)
+}
+
+// ankiToOrg converts HTML of a note field into org lines. Image
+// sources are linked using provided prefix and added to media.
+func ankiToOrg(field, prefix string, media map[string]bool) string {
+	text := ankiSoundRegexp.ReplaceAllString(field, "")
+	text = ankiBreakRegexp.ReplaceAllString(text, "\n")
+	text = ankiImageRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		src := html.UnescapeString(ankiImageRegexp.FindStringSubmatch(match)[1])
+		if strings.Contains(src, "://") {
+			return "[[" + src + "]]"
+		}
+
+		name := filepath.Base(src)
+		media[name] = true
+		return "[[" + (&url.URL{Path: prefix + name}).String() + "]]"
+	})
+	text = ankiBoldRegexp.ReplaceAllString(text, "*")
+	text = ankiEmRegexp.ReplaceAllString(text, "/")
+	text = ankiTagRegexp.ReplaceAllString(text, "")
+	text = strings.Replace(html.UnescapeString(text), " ", " ", -1)
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		// keep answers from starting a headline
+		if headlineRegexp.MatchString(line) {
+			line = "- " + line
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ankiTags converts space separated Anki tags into headline tags,
+// hierarchy separators and other unsupported ASCII characters are
+// replaced with "_". Tags with non-ASCII characters can't be parsed
+// in headlines and are returned as dropped.
+func ankiTags(tags string) ([]string, []string) {
+	var result, dropped []string
+	for _, tag := range strings.Fields(tags) {
+		if strings.IndexFunc(tag, func(r rune) bool { return r >= utf8.RuneSelf }) != -1 {
+			dropped = append(dropped, tag)
+			continue
+		}
+
+		tag = strings.Replace(tag, "::", "_", -1)
+		tag = strings.Map(func(r rune) rune {
+			if isTagRune(r) {
+				return r
+			}
+			return '_'
+		}, tag)
+		result = append(result, tag)
+	}
+
+	return result, dropped
+}
+
+// ankiFileName replaces characters that are not safe in file names.
+func ankiFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	if name == "" || name == "." || name == ".." || strings.HasPrefix(name, ".") {
+		name = "_" + name
+	}
+
+	return name
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("anki: %s", err)
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, int64(f.UncompressedSize64)+1))
+	if err != nil {
+		return nil, fmt.Errorf("anki: %s", err)
+	}
+
+	return data, nil
+}
+
+// ankiFile is a deck or a media file written by an import, media
+// content is read from a package entry.
+type ankiFile struct {
+	path  string
+	data  []byte
+	entry *zip.File
+}
+
+// writeAnkiFiles writes all files of an import or none of them.
+// Existing files are reported before anything is written, written
+// files are removed if a later write fails.
+func writeAnkiFiles(files []ankiFile) error {
+	for _, f := range files {
+		if _, err := os.Lstat(f.path); err == nil {
+			return fmt.Errorf("anki: %s already exists", f.path)
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("file: %s", err)
+		}
+	}
+
+	written := make([]string, 0, len(files))
+	for _, f := range files {
+		data := f.data
+		if f.entry != nil {
+			content, err := readZipFile(f.entry)
+			if err != nil {
+				removeFiles(written)
+				return err
+			}
+			data = content
+		}
+
+		if err := writeNewFile(f.path, data); err != nil {
+			removeFiles(written)
+			return err
+		}
+		written = append(written, f.path)
+	}
+
+	return nil
+}
+
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		os.Remove(path) // nolint: errcheck
+	}
+}
+
+// writeNewFile writes a file creating missing folders, existing
+// files are not overwritten.
+func writeNewFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("file: %s", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("file: %s", err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path) // nolint: errcheck
+		return fmt.Errorf("file: %s", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(path) // nolint: errcheck
+		return fmt.Errorf("file: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package leaf
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportAnki(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	result, err := ImportAnki("./fixtures/anki.apkg", dir, SRSFSRS)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{filepath.Join(dir, "Geography.org"), filepath.Join(dir, "Japanese", "Verbs.org")}, result.Files)
+	assert.Equal(t, 71, result.Cards)
+	assert.Equal(t, 1, result.Media)
+	assert.FileExists(t, filepath.Join(dir, "200.png"))
+	assert.Equal(t, []string{"動詞"}, result.DroppedTags)
+
+	t.Run("Decks", func(t *testing.T) {
+		decks, err := OpenDeck(filepath.Join(dir, "Japanese", "Verbs.org"), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks, 1)
+
+		deck := decks[0]
+		assert.Equal(t, "Verbs", deck.Name)
+		assert.Equal(t, SRS(SRSFSRS), deck.Algorithm)
+		assert.Equal(t, RatingTypeSelf, deck.RatingType)
+		require.Len(t, deck.Cards, 69)
+
+		card := deck.Cards[0]
+		assert.Equal(t, "anki-1546300800000", card.ID)
+		assert.Equal(t, "*食べる*", card.Question)
+		assert.Equal(t, []string{"to eat", "to consume"}, card.Sides)
+		assert.Equal(t, []string{"japanese_verbs", "common"}, card.Tags)
+		assert.Equal(t, "anki-1546300800000#r", deck.Cards[1].ID)
+		// non-ASCII tag of the second note is dropped
+		assert.Equal(t, "飲む", deck.Cards[2].Question)
+		assert.Equal(t, []string{"japanese_verbs", "common"}, deck.Cards[2].Tags)
+
+		decks, err = OpenDeck(filepath.Join(dir, "Geography.org"), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks[0].Cards, 2)
+
+		cloze := decks[0].Cards[1]
+		assert.Equal(t, "anki-1546300800200#c2", cloze.ID)
+		assert.Equal(t, "Tokyo is the capital of [country] [[/images/200.png]]", cloze.Question)
+		assert.Equal(t, []string{"Japan"}, cloze.Sides)
+	})
+
+	t.Run("Reviews", func(t *testing.T) {
+		require.Len(t, result.Reviews, 4)
+
+		review := result.Reviews[0]
+		assert.Equal(t, "Japanese/Verbs", review.Deck)
+		assert.Equal(t, "anki-1546300800000", review.Card)
+		assert.Equal(t, time.Date(2019, 1, 1, 0, 0, 1, 0, time.UTC), review.ReviewedAt)
+		assert.Equal(t, ReviewScoreAgain, review.Score)
+		assert.InDelta(t, 60.0/86400, review.Interval, 0.0001)
+
+		assert.Equal(t, "anki-1546300800200#c1", result.Reviews[1].Card)
+		assert.Equal(t, ReviewScoreEasy, result.Reviews[1].Score)
+		assert.Equal(t, 1.0, result.Reviews[1].Rating)
+		assert.Equal(t, ReviewScoreGood, result.Reviews[2].Score)
+		assert.InDelta(t, 0.59, result.Reviews[2].Rating, 0.01)
+		assert.Equal(t, 0.6, result.Reviews[3].Rating)
+	})
+
+	t.Run("Save", func(t *testing.T) {
+		tmpfile, err := ioutil.TempFile("", "leaf.db")
+		require.NoError(t, err)
+		defer os.Remove(tmpfile.Name())
+
+		db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+		require.NoError(t, err)
+		defer db.Close()
+
+		clock := NewSimulatedClock(time.Date(2019, 1, 10, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, result.Save(db, clock))
+
+		dm, err := NewDeckManager(DeckSource{Roots: []string{dir}}, db, OutputFormatOrg, clock)
+		require.NoError(t, err)
+
+		reviews, err := dm.Reviews("Japanese/Verbs")
+		require.NoError(t, err)
+		assert.Len(t, reviews, 3)
+
+		stats, err := dm.DeckStats("Japanese/Verbs")
+		require.NoError(t, err)
+		assert.Equal(t, "anki-1546300800000", stats[0].ID)
+		assert.True(t, stats[0].NextReviewAt().After(time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)))
+	})
+
+	_, err = ImportAnki("./fixtures/anki.apkg", dir, SRSFSRS)
+	assert.Error(t, err)
+}
+
+func TestImportAnkiCollision(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// second deck file collides with an existing one
+	existing := filepath.Join(dir, "Japanese", "Verbs.org")
+	require.NoError(t, os.MkdirAll(filepath.Dir(existing), 0755))
+	require.NoError(t, ioutil.WriteFile(existing, []byte("* Verbs\n"), 0644))
+
+	_, err = ImportAnki("./fixtures/anki.apkg", dir, SRSFSRS)
+	assert.EqualError(t, err, "anki: "+existing+" already exists")
+
+	for _, name := range []string{"Geography.org", "200.png"} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+	content, err := ioutil.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "* Verbs\n", string(content))
+
+	require.NoError(t, os.Remove(existing))
+	result, err := ImportAnki("./fixtures/anki.apkg", dir, SRSFSRS)
+	require.NoError(t, err)
+	assert.Len(t, result.Files, 2)
+}
+
+func TestImportAnkiInvalidRevlog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	archive, err := zip.OpenReader("./fixtures/anki.apkg")
+	require.NoError(t, err)
+	defer archive.Close()
+
+	// package without a review log table
+	pkg := filepath.Join(dir, "broken.apkg")
+	out, err := os.Create(pkg)
+	require.NoError(t, err)
+	w := zip.NewWriter(out)
+	for _, f := range archive.File {
+		data, err := readZipFile(f)
+		require.NoError(t, err)
+		if f.Name == "collection.anki2" {
+			data = bytes.Replace(data, []byte("revlog"), []byte("rxvlog"), -1)
+		}
+
+		entry, err := w.Create(f.Name)
+		require.NoError(t, err)
+		_, err = entry.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, out.Close())
+
+	root := filepath.Join(dir, "decks")
+	_, err = ImportAnki(pkg, root, SRSFSRS)
+	assert.EqualError(t, err, "sqlite: table revlog is not found")
+
+	_, err = os.Stat(root)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,54 @@
+package leaf
+
+import "time"
+
+// Clock provides current time for review scheduling.
+type Clock interface {
+	// Now returns current time.
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is a Clock backed by the system time.
+var SystemClock Clock = systemClock{}
+
+// SimulatedClock is a Clock that only moves when requested. It's
+// useful for simulations and deterministic replays of reviews.
+type SimulatedClock struct {
+	now time.Time
+}
+
+// NewSimulatedClock returns a new SimulatedClock set to a given time.
+func NewSimulatedClock(now time.Time) *SimulatedClock {
+	return &SimulatedClock{now}
+}
+
+// Now returns current simulated time.
+func (c *SimulatedClock) Now() time.Time {
+	return c.now
+}
+
+// Set moves clock to a given time.
+func (c *SimulatedClock) Set(now time.Time) {
+	c.now = now
+}
+
+// Advance moves clock forward by a given duration.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// timeNow returns current time of a clock, system time is used for
+// missing clocks.
+func timeNow(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+
+	return clock.Now()
+}
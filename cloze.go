@@ -0,0 +1,85 @@
+package leaf
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/niklasfasching/go-org/org"
+)
+
+// clozeRegexp matches cloze deletions, e.g. {{c1::word}} or
+// {{c1::word::hint}}.
+var clozeRegexp = regexp.MustCompile(`\{\{c(\d+)::(.*?)(?:::(.*?))?\}\}`)
+
+// cloze is a single card generated from a text with cloze
+// deletions.
+type cloze struct {
+	number   int
+	question string
+	answers  []string
+}
+
+// parseClozes returns a cloze for each deletion number found in a
+// text ordered by number. Question of a cloze has deletions with
+// the same number replaced with a blank (or a hint if provided),
+// other deletions are revealed. Nil is returned for texts without
+// deletions.
+func parseClozes(text string) []cloze {
+	matches := clozeRegexp.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	numbers := make([]int, 0, len(matches))
+	seen := make(map[int]bool, len(matches))
+	for _, m := range matches {
+		number, _ := strconv.Atoi(m[1])
+		if !seen[number] {
+			seen[number] = true
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+
+	result := make([]cloze, 0, len(numbers))
+	for _, number := range numbers {
+		c := cloze{number: number}
+		c.question = clozeRegexp.ReplaceAllStringFunc(text, func(match string) string {
+			m := clozeRegexp.FindStringSubmatch(match)
+			if n, _ := strconv.Atoi(m[1]); n != number {
+				return m[2]
+			}
+
+			c.answers = append(c.answers, m[2])
+			if m[3] != "" {
+				return "[" + m[3] + "]"
+			}
+			return "[...]"
+		})
+		result = append(result, c)
+	}
+
+	return result
+}
+
+// clozeCardID returns ID of a card generated for a cloze deletion
+// number of a headline.
+func clozeCardID(id string, number int) string {
+	return id + "#c" + strconv.Itoa(number)
+}
+
+// renderOrg renders org text using provided output format.
+func renderOrg(text string, format OutputFormat) string {
+	if format != OutputFormatHTML {
+		return text
+	}
+
+	out, err := org.New().Silent().Parse(strings.NewReader(text), "./").Write(org.NewHTMLWriter())
+	if err != nil {
+		return text
+	}
+
+	return out
+}
@@ -0,0 +1,24 @@
+package leaf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseClozes(t *testing.T) {
+	assert.Nil(t, parseClozes("no deletions {{here}}"))
+
+	clozes := parseClozes("{{c2::Tokyo}} is the capital of {{c1::Japan::country}}, {{c2::Osaka}} is not")
+	assert.Equal(t, []cloze{
+		{1, "Tokyo is the capital of [country], Osaka is not", []string{"Japan"}},
+		{2, "[...] is the capital of Japan, [...] is not", []string{"Tokyo", "Osaka"}},
+	}, clozes)
+
+	assert.Equal(t, "abc#c2", clozeCardID("abc", 2))
+}
+
+func TestRenderOrg(t *testing.T) {
+	assert.Equal(t, "/foo/ [...]", renderOrg("/foo/ [...]", OutputFormatOrg))
+	assert.Contains(t, renderOrg("/foo/ [...]", OutputFormatHTML), "<p>\n<em>foo</em> [")
+}
@@ -4,36 +4,58 @@ import (
 	"flag"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/ap4y/leaf"
 	"github.com/ap4y/leaf/ui"
 )
 
 var (
-	decks   = flag.String("decks", ".", "deck files location")
+	decks   = listFlag{}
+	include = listFlag{}
+	exclude = listFlag{}
+
 	db      = flag.String("db", "leaf.db", "stats database location")
 	addr    = flag.String("addr", ":8000", "addr for Web UI")
 	devMode = flag.Bool("dev", false, "use local dev assets")
+
+	autoMigrate = flag.Bool("auto-migrate", false, "migrate stats saved with a different algorithm")
 )
 
 func main() {
+	flag.Var(&decks, "decks", "deck files location, can be repeated or comma separated (default .)")
+	flag.Var(&include, "include", "glob patterns of included deck files, e.g. languages/**/*.org")
+	flag.Var(&exclude, "exclude", "glob patterns of excluded deck files")
 	flag.Parse()
 
-	db, err := leaf.OpenBoltStore(*db)
+	if len(decks) == 0 {
+		decks = listFlag{"."}
+	}
+
+	policy := leaf.MismatchRefuse
+	if *autoMigrate {
+		policy = leaf.MismatchMigrate
+	}
+
+	db, err := leaf.OpenBoltStore(*db, policy)
 	if err != nil {
 		log.Fatal("Failed to open stats DB: ", err)
 	}
 
 	defer db.Close()
 
-	dm, err := leaf.NewDeckManager(*decks, db, leaf.OutputFormatHTML)
+	dm, err := leaf.NewDeckManager(leaf.DeckSource{Roots: decks, Include: include, Exclude: exclude}, db, leaf.OutputFormatHTML, leaf.SystemClock)
 	if err != nil {
 		log.Fatal("Failed to initialise deck manager: ", err)
 	}
 
+	for _, d := range dm.Duplicates() {
+		log.Println("Warning:", d)
+	}
+
 	srv := ui.NewServer(dm)
 	handler := srv.Handler(*devMode)
-	fs := http.FileServer(http.Dir(*decks))
+	fs := http.FileServer(deckDirs(decks))
 	handler.Handle("/images/", http.StripPrefix("/images", fs))
 
 	log.Println("Serving HTTP on", *addr)
@@ -41,3 +63,35 @@ func main() {
 		log.Fatal("Failed to render: ", err)
 	}
 }
+
+// listFlag is a flag that can be repeated or contain comma
+// separated values.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// deckDirs serves files from the first deck root that has them.
+type deckDirs []string
+
+func (d deckDirs) Open(name string) (http.File, error) {
+	var err error
+	for _, root := range d {
+		var f http.File
+		if f, err = http.Dir(root).Open(name); err == nil {
+			return f, nil
+		}
+	}
+
+	return nil, err
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ap4y/leaf"
+)
+
+// importAnki converts an Anki package into decks of the first deck
+// root and saves stats of reviewed cards.
+func importAnki(filename string, algo leaf.SRS, db leaf.StatsStore) {
+	if filename == "" {
+		log.Fatal("Missing file")
+	}
+
+	if algo == "" {
+		algo = leaf.SRSSupermemo2PlusCustom
+	}
+
+	result, err := leaf.ImportAnki(filename, decks[0], algo)
+	if err != nil {
+		log.Fatal("Failed to import Anki package: ", err)
+	}
+
+	if err := result.Save(db, leaf.SystemClock); err != nil {
+		log.Fatal("Failed to save stats: ", err)
+	}
+
+	if len(result.DroppedTags) > 0 {
+		fmt.Println("Dropped tags that can't be used in headlines:", strings.Join(result.DroppedTags, " "))
+	}
+
+	for _, file := range result.Files {
+		fmt.Println("Created", file)
+	}
+	fmt.Printf("Imported %d cards, %d media files and %d reviews\n", result.Cards, result.Media, len(result.Reviews))
+}
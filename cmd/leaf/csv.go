@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ap4y/leaf"
+)
+
+// importCSV converts a CSV or TSV file into an org deck printed to
+// stdout.
+func importCSV(deckName, filename string, algo leaf.SRS) {
+	if filename == "" {
+		log.Fatal("Missing file")
+	}
+
+	if algo == "" {
+		algo = leaf.SRSSupermemo2PlusCustom
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		log.Fatal("Failed to open file: ", err)
+	}
+	defer f.Close()
+
+	answers := answerColumns
+	if len(answers) == 0 {
+		answers = listFlag{"2"}
+	}
+
+	count, err := leaf.ImportCSV(f, os.Stdout, deckName, leaf.CSVImport{
+		Comma:    comma(filename),
+		Header:   *header,
+		ID:       *idColumn,
+		Question: *questionColumn,
+		Answers:  answers,
+		Tags:     *tagColumn,
+		Properties: [][2]string{
+			{"ALGORITHM", string(algo)},
+			{"RATER", string(leaf.RatingTypeAuto)},
+			{"PER_REVIEW", "20"},
+		},
+	})
+	if err != nil {
+		log.Fatal("Failed to import cards: ", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %d cards\n", count)
+}
+
+// exportCSV writes cards of a deck along with their stats into a
+// file, stdout is used if file is not provided.
+func exportCSV(dm *leaf.DeckManager, deckName, filename string) {
+	out := os.Stdout
+	if filename != "" {
+		f, err := os.Create(filename)
+		if err != nil {
+			log.Fatal("Failed to create file: ", err)
+		}
+		out = f
+	}
+
+	if err := dm.ExportCSV(deckName, out, comma(filename)); err != nil {
+		log.Fatal("Failed to export cards: ", err)
+	}
+
+	if out != os.Stdout {
+		if err := out.Close(); err != nil {
+			log.Fatal("Failed to write file: ", err)
+		}
+	}
+}
+
+// comma returns field delimiter for a file.
+func comma(filename string) rune {
+	switch *delimiter {
+	case "":
+		if strings.EqualFold(filepath.Ext(filename), ".tsv") {
+			return '\t'
+		}
+		return ','
+	case "tab", `\t`:
+		return '\t'
+	}
+
+	r, _ := utf8.DecodeRuneInString(*delimiter)
+	return r
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ap4y/leaf"
+)
+
+func gc(dm *leaf.DeckManager) {
+	orphans, err := dm.OrphanedStats()
+	if err != nil {
+		log.Fatal("Failed to find orphaned stats: ", err)
+	}
+
+	if len(orphans) == 0 {
+		fmt.Println("No orphaned stats found")
+		return
+	}
+
+	relinked := 0
+	w := tabwriter.NewWriter(os.Stdout, 5, 5, 5, ' ', 0)
+	fmt.Fprintln(w, "Deck\tCard\tAction")
+	for _, o := range orphans {
+		action := "delete"
+		if o.Match != "" {
+			action = fmt.Sprintf("re-link to %s (%s, %.0f%%)", o.Question, o.MatchDeck, o.Similarity*100)
+			relinked++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", o.Deck, o.Card, action)
+	}
+	w.Flush()
+
+	if *dryRun {
+		return
+	}
+
+	fmt.Printf("Re-link %d and delete %d records? [y/N]: ", relinked, len(orphans)-relinked)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+		return
+	}
+
+	if err := dm.CollectGarbage(orphans); err != nil {
+		log.Fatal("Failed to collect garbage: ", err)
+	}
+
+	fmt.Printf("Re-linked %d and deleted %d records\n", relinked, len(orphans)-relinked)
+}
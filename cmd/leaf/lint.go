@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ap4y/leaf"
+)
+
+// lint prints diagnostics of provided deck files or all files of deck
+// roots and exits with a non-zero code if problems other than
+// warnings were found.
+func lint(files []string) {
+	var diagnostics []leaf.Diagnostic
+	if len(files) == 0 {
+		var err error
+		diagnostics, err = leaf.DeckSource{Roots: decks, Include: include, Exclude: exclude}.Validate()
+		if err != nil {
+			log.Fatal("Failed to validate decks: ", err)
+		}
+	}
+
+	for _, file := range files {
+		result, err := leaf.ValidateDeck(file, decks)
+		if err != nil {
+			log.Fatal("Failed to validate deck: ", err)
+		}
+		diagnostics = append(diagnostics, result...)
+	}
+
+	failed := false
+	for _, d := range diagnostics {
+		fmt.Println(d)
+		failed = failed || !d.Warning
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/ap4y/leaf"
 	"github.com/ap4y/leaf/ui"
@@ -14,36 +17,143 @@ import (
 )
 
 var (
-	decks = flag.String("decks", ".", "deck files location")
-	db    = flag.String("db", "leaf.db", "stats database location")
+	decks   = listFlag{}
+	include = listFlag{}
+	exclude = listFlag{}
+
+	db = flag.String("db", "leaf.db", "stats database location")
+
+	dryRun      = flag.Bool("dry-run", false, "report migration, optimization, repair or gc results without saving them")
+	from        = flag.String("from", "", "algorithm of untagged stats, defaults to deck's ALGORITHM")
+	autoMigrate = flag.Bool("auto-migrate", false, "migrate stats saved with a different algorithm")
+	tags        = flag.String("tags", "", "review cards matching tag filter, e.g. '+verbs -irregular'")
+
+	header         = flag.Bool("header", false, "imported file has a header row")
+	delimiter      = flag.String("delimiter", "", "field delimiter of imported and exported files, defaults to tab for .tsv files and comma otherwise")
+	idColumn       = flag.String("id-column", "", "imported column with card IDs, index or header name")
+	questionColumn = flag.String("question-column", "1", "imported column with questions, index or header name")
+	tagColumn      = flag.String("tag-column", "", "imported column with tags, index or header name")
+	answerColumns  = listFlag{}
+
+	days      = flag.Int("days", 365, "amount of simulated days")
+	cards     = flag.Int("cards", 1000, "amount of simulated cards")
+	newPerDay = flag.Int("new", 10, "amount of simulated new cards per day")
+	budget    = flag.Int("budget", 200, "simulated daily review budget, 0 for unlimited")
+	curve     = flag.String("curve", "exp", "simulated forgetting curve: exp or power")
+	stability = flag.Float64("stability", 1, "simulated stability of new cards in days")
+	growth    = flag.Float64("growth", 2.5, "simulated stability growth for recalled cards")
+	lapse     = flag.Float64("lapse", 0.3, "simulated stability decay for forgotten cards")
+	seed      = flag.Int64("seed", 1, "seed for simulated recall outcomes")
+	format    = flag.String("format", "table", "simulation output format: table, csv or json")
 )
 
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [args] [stats|review] [deck_name]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [args] [stats|review|reviews|migrate|replay|check|optimize|repair] [deck_name] [algorithm]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [args] simulate [algorithm...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [args] gc\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [args] ids\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [args] import [deck_name] [file] [algorithm]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [args] export [deck_name] [file]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [args] import-anki [file.apkg] [algorithm]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [args] lint [file...]\n", os.Args[0])
 		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s -decks ./fixtures review Hiragana\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s -decks ./fixtures -tags '+verbs -irregular' review Japanese\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s -decks ./fixtures -dry-run migrate Hiragana fsrs\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s -decks ./fixtures -dry-run replay Hiragana ebisu\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s -decks ./fixtures -dry-run optimize Hiragana\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s -days 90 -format csv simulate sm2+c fsrs\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s -header -answer-columns reading,meaning -tag-column tags import Vocabulary words.tsv > vocabulary.org\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s -decks ./fixtures export Hiragana hiragana.csv\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s -decks ./decks import-anki collection.apkg fsrs\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Example: %s -decks ./fixtures lint\n", os.Args[0])
 		fmt.Fprintln(flag.CommandLine.Output(), "Optional arguments:")
 		flag.PrintDefaults()
 	}
+	flag.Var(&decks, "decks", "deck files location, can be repeated or comma separated (default .)")
+	flag.Var(&include, "include", "glob patterns of included deck files, e.g. languages/**/*.org")
+	flag.Var(&exclude, "exclude", "glob patterns of excluded deck files")
+	flag.Var(&answerColumns, "answer-columns", "imported columns with answers, indexes, header names or name patterns (default 2)")
 	flag.Parse()
 
+	if len(decks) == 0 {
+		decks = listFlag{"."}
+	}
+
+	if flag.Arg(0) == "simulate" {
+		simulate(flag.Args()[1:])
+		return
+	}
+
+	if flag.Arg(0) == "lint" {
+		lint(flag.Args()[1:])
+		return
+	}
+
 	deckName := flag.Arg(1)
-	if deckName == "" {
+	if deckName == "" && flag.Arg(0) != "gc" && flag.Arg(0) != "ids" && flag.Arg(0) != "import-anki" {
 		log.Fatal("Missing deck name")
 	}
 
-	db, err := leaf.OpenBoltStore(*db)
+	if flag.Arg(0) == "import" {
+		importCSV(deckName, flag.Arg(2), leaf.SRS(flag.Arg(3)))
+		return
+	}
+
+	policy := leaf.MismatchRefuse
+	if *autoMigrate {
+		policy = leaf.MismatchMigrate
+	}
+
+	db, err := leaf.OpenBoltStore(*db, policy)
 	if err != nil {
 		log.Fatal("Failed to open stats DB: ", err)
 	}
 
 	defer db.Close()
 
-	dm, err := leaf.NewDeckManager(*decks, db, leaf.OutputFormatOrg)
+	if flag.Arg(0) == "import-anki" {
+		importAnki(flag.Arg(1), leaf.SRS(flag.Arg(2)), db)
+		return
+	}
+
+	dm, err := leaf.NewDeckManager(leaf.DeckSource{Roots: decks, Include: include, Exclude: exclude}, db, leaf.OutputFormatOrg, leaf.SystemClock)
 	if err != nil {
 		log.Fatal("Failed to initialise deck manager: ", err)
 	}
 
+	for _, d := range dm.Duplicates() {
+		log.Println("Warning:", d)
+	}
+
+	if flag.Arg(0) == "gc" {
+		gc(dm)
+		return
+	}
+
+	if flag.Arg(0) == "ids" {
+		if err := dm.WriteIDs(); err != nil {
+			log.Fatal("Failed to write card IDs: ", err)
+		}
+		return
+	}
+
+	deckCards, err := dm.Cards(deckName)
+	if err != nil {
+		log.Fatal("Failed to get cards: ", err)
+	}
+
+	questions := make(map[string]string, len(deckCards))
+	for _, card := range deckCards {
+		questions[card.ID] = card.Question
+	}
+	question := func(id string) string {
+		if q, ok := questions[id]; ok {
+			return q
+		}
+		return id
+	}
+
 	switch flag.Arg(0) {
 	case "stats":
 		stats, err := dm.DeckStats(deckName)
@@ -61,8 +171,24 @@ func main() {
 			fmt.Fprintf(w, "%s\t%s\n", s.Question, stat)
 		}
 		w.Flush()
+	case "reviews":
+		reviews, err := dm.Reviews(deckName)
+		if err != nil {
+			log.Fatal("Failed to get review log: ", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 5, 5, 5, ' ', 0)
+		fmt.Fprintln(w, "Reviewed at\tCard\tScore\tRating\tAnswer\tElapsed\tInterval\tAlgorithm")
+		for _, r := range reviews {
+			fmt.Fprintf(
+				w, "%s\t%s\t%d\t%.2f\t%s\t%s\t%.2f -> %.2f\t%s\n",
+				r.ReviewedAt.Format(time.RFC822), question(r.Card), r.Score, r.Rating, r.Answer,
+				r.Elapsed.Round(time.Millisecond), r.PrevInterval, r.Interval, r.Algorithm,
+			)
+		}
+		w.Flush()
 	case "review":
-		session, err := dm.ReviewSession(deckName)
+		session, err := dm.ReviewSession(deckName, *tags)
 		if err != nil {
 			log.Fatal("Failed to create review session: ", err)
 		}
@@ -77,7 +203,117 @@ func main() {
 		if err := u.Render(ui.NewSessionState(session)); err != nil {
 			log.Fatal("Failed to render: ", err)
 		}
+	case "export":
+		exportCSV(dm, deckName, flag.Arg(2))
+	case "check":
+		inconsistent, err := dm.InconsistentCards(deckName)
+		if err != nil {
+			log.Fatal("Failed to check card stats: ", err)
+		}
+
+		if len(inconsistent) == 0 {
+			fmt.Println("All cards are consistent")
+			break
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 5, 5, 5, ' ', 0)
+		fmt.Fprintln(w, "Card\tAlgorithm")
+		for card, srs := range inconsistent {
+			fmt.Fprintf(w, "%s\t%s\n", question(card), srs)
+		}
+		w.Flush()
+	case "migrate", "replay":
+		algo := flag.Arg(2)
+		if algo == "" {
+			log.Fatal("Missing algorithm")
+		}
+
+		var migrations []leaf.StatsMigration
+		if flag.Arg(0) == "replay" {
+			migrations, err = dm.ReplayDeck(deckName, leaf.SRS(algo), *dryRun)
+		} else {
+			migrations, err = dm.MigrateDeck(deckName, leaf.SRS(*from), leaf.SRS(algo), *dryRun)
+		}
+		if err != nil {
+			log.Fatal("Failed to migrate stats: ", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 5, 5, 5, ' ', 0)
+		fmt.Fprintln(w, "Card\tNext review\tMigrated review")
+		for _, m := range migrations {
+			nextReviewAt := "-"
+			if !m.NextReviewAt.IsZero() {
+				nextReviewAt = m.NextReviewAt.Format(time.RFC822)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", question(m.Card), nextReviewAt, m.MigratedReviewAt.Format(time.RFC822))
+		}
+		w.Flush()
+
+		if !*dryRun {
+			fmt.Printf("Migrated %d cards, deck's ALGORITHM property is set to %s\n", len(migrations), algo)
+		}
+	case "repair":
+		repairs, err := dm.RepairDeck(deckName, *dryRun)
+		if err != nil {
+			log.Fatal("Failed to repair stats: ", err)
+		}
+
+		if len(repairs) == 0 {
+			fmt.Println("No duplicate stats found")
+			break
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 5, 5, 5, ' ', 0)
+		fmt.Fprintln(w, "Card\tMerged records\tNext review")
+		for _, r := range repairs {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", r.Question, len(r.Merged), r.Stats.NextReviewAt().Format(time.RFC822))
+		}
+		w.Flush()
+
+		if !*dryRun {
+			fmt.Printf("Repaired %d cards\n", len(repairs))
+		}
+	case "optimize":
+		result, err := dm.OptimizeDeck(deckName, *dryRun)
+		if err != nil {
+			log.Fatal("Failed to optimize parameters: ", err)
+		}
+
+		names := make([]string, 0, len(result.Params))
+		for name := range result.Params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := tabwriter.NewWriter(os.Stdout, 5, 5, 5, ' ', 0)
+		fmt.Fprintln(w, "Property\tValue")
+		for _, name := range names {
+			fmt.Fprintf(w, "%s\t%s\n", name, result.Params[name])
+		}
+		w.Flush()
+
+		fmt.Printf("Log-loss over %d reviews: %.4f -> %.4f\n", result.Reviews, result.InitialLoss, result.Loss)
+		if !*dryRun {
+			fmt.Println("Updated deck's property drawer")
+		}
 	default:
 		log.Fatal("unknown command")
 	}
 }
+
+// listFlag is a flag that can be repeated or contain comma
+// separated values.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs leaf instead of tests when LEAF_MAIN is set, tests
+// use it to run commands in a separate process.
+func TestMain(m *testing.M) {
+	if os.Getenv("LEAF_MAIN") != "" {
+		os.Args = append([]string{"leaf"}, os.Args[1:]...)
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func runLeaf(t *testing.T, args ...string) (string, error) {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "LEAF_MAIN=1")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestSubDeckCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	content := "* Kana\n:PROPERTIES:\n:SUBDECKS: t\n:END:\n" +
+		"** A-row\n*** あ\n:PROPERTIES:\n:ID: a\n:END:\na\n" +
+		"** K-row\n*** か\n:PROPERTIES:\n:ID: ka\n:END:\nka\n*** き\n:PROPERTIES:\n:ID: ki\n:END:\nki\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "kana.org"), []byte(content), 0644))
+	db := filepath.Join(dir, "leaf.db")
+
+	out, err := runLeaf(t, "-decks", dir, "-db", db, "stats", "Kana/K-row")
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "か")
+	assert.Contains(t, out, "き")
+	assert.NotContains(t, out, "あ")
+
+	out, err = runLeaf(t, "-decks", dir, "-db", db, "reviews", "Kana/K-row")
+	require.NoError(t, err, out)
+
+	out, err = runLeaf(t, "-decks", dir, "-db", db, "export", "Kana/K-row")
+	require.NoError(t, err, out)
+	assert.Contains(t, out, "ka,か,ka,")
+	assert.NotContains(t, out, "あ")
+
+	out, err = runLeaf(t, "-decks", dir, "-db", db, "stats", "Kana/N-row")
+	assert.Error(t, err)
+	assert.Contains(t, out, "deck not found")
+}
+
+func TestSimulateUnknownFormat(t *testing.T) {
+	// format is rejected before a long simulation starts
+	out, err := runLeaf(t, "-format", "xml", "-days", "100000", "-cards", "100000", "simulate", "sm2")
+	assert.Error(t, err)
+	assert.Contains(t, out, "Unknown output format: xml")
+}
+
+func TestLintWarnings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// duplicate questions are reviewed separately and only warned
+	deck := filepath.Join(dir, "deck.org")
+	content := "* Deck\n** Question\n:PROPERTIES:\n:ID: 1\n:END:\nfoo\n** Question\n:PROPERTIES:\n:ID: 2\n:END:\nbar\n"
+	require.NoError(t, ioutil.WriteFile(deck, []byte(content), 0644))
+
+	out, err := runLeaf(t, "-decks", dir, "lint")
+	require.NoError(t, err, out)
+	assert.Contains(t, out, deck+`:7: warning: duplicate question "Question", first defined on line 2`)
+
+	content = strings.Replace(content, ":ID: 2", ":ID: 1", 1)
+	require.NoError(t, ioutil.WriteFile(deck, []byte(content), 0644))
+
+	out, err = runLeaf(t, "-decks", dir, "lint")
+	assert.Error(t, err)
+	assert.Contains(t, out, deck+":9: duplicate ID 1, first defined on line 4")
+}
+
+func TestReadOnlyCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	deck := filepath.Join(dir, "words.org")
+	content := "* Words\n** cat\nneko\n"
+	require.NoError(t, ioutil.WriteFile(deck, []byte(content), 0644))
+	db := filepath.Join(dir, "leaf.db")
+
+	commands := [][]string{
+		{"stats", "Words"},
+		{"reviews", "Words"},
+		{"check", "Words"},
+		{"export", "Words"},
+		{"-dry-run", "migrate", "Words", "fsrs"},
+		{"-dry-run", "replay", "Words", "fsrs"},
+		{"-dry-run", "repair", "Words"},
+		{"-dry-run", "gc"},
+	}
+	for _, args := range commands {
+		out, err := runLeaf(t, append([]string{"-decks", dir, "-db", db}, args...)...)
+		require.NoError(t, err, out)
+
+		data, err := ioutil.ReadFile(deck)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data), strings.Join(args, " "))
+	}
+
+	out, err := runLeaf(t, "-decks", dir, "-db", db, "ids")
+	require.NoError(t, err, out)
+
+	data, err := ioutil.ReadFile(deck)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "** cat\n:PROPERTIES:\n:ID: ")
+}
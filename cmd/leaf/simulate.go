@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ap4y/leaf"
+)
+
+func simulate(algorithms []string) {
+	switch *format {
+	case "table", "csv", "json":
+	default:
+		log.Fatal("Unknown output format: ", *format)
+	}
+
+	learner := leaf.DefaultLearnerModel()
+	switch *curve {
+	case "exp":
+		learner.Curve = leaf.ExponentialCurve
+	case "power":
+		learner.Curve = leaf.PowerCurve
+	default:
+		log.Fatal("Unknown forgetting curve: ", *curve)
+	}
+	learner.InitialStability = *stability
+	learner.Growth = *growth
+	learner.LapseFactor = *lapse
+
+	config := leaf.SimulationConfig{
+		Days:         *days,
+		Cards:        *cards,
+		NewPerDay:    *newPerDay,
+		ReviewBudget: *budget,
+		Learner:      learner,
+		Seed:         *seed,
+	}
+
+	srs := leaf.AvailableSRS()
+	if len(algorithms) > 0 {
+		srs = make([]leaf.SRS, len(algorithms))
+		for idx, algo := range algorithms {
+			srs[idx] = leaf.SRS(algo)
+		}
+	}
+
+	results := make([]*leaf.SimulationResult, len(srs))
+	for idx, algo := range srs {
+		res, err := leaf.Simulate(algo, config)
+		if err != nil {
+			log.Fatal("Failed to simulate reviews: ", err)
+		}
+		results[idx] = res
+	}
+
+	switch *format {
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 5, 5, 5, ' ', 0)
+		fmt.Fprint(w, "Day")
+		for _, res := range results {
+			fmt.Fprintf(w, "\t%s", res.Algorithm)
+		}
+		fmt.Fprintln(w)
+
+		for day := 0; day < config.Days; day++ {
+			fmt.Fprint(w, day+1)
+			for _, res := range results {
+				fmt.Fprintf(w, "\t%d", res.Days[day].Reviews)
+			}
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprint(w, "Total reviews")
+		for _, res := range results {
+			fmt.Fprintf(w, "\t%d", res.TotalReviews)
+		}
+		fmt.Fprintln(w)
+
+		fmt.Fprint(w, "Average retention")
+		for _, res := range results {
+			fmt.Fprintf(w, "\t%.2f%%", res.AverageRetention*100)
+		}
+		fmt.Fprintln(w)
+		w.Flush()
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"algorithm", "day", "reviews", "new", "lapses", "retention"})
+		for _, res := range results {
+			for _, day := range res.Days {
+				w.Write([]string{
+					string(res.Algorithm),
+					strconv.Itoa(day.Day),
+					strconv.Itoa(day.Reviews),
+					strconv.Itoa(day.New),
+					strconv.Itoa(day.Lapses),
+					strconv.FormatFloat(day.Retention, 'f', 4, 64),
+				})
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Fatal("Failed to write csv: ", err)
+		}
+	case "json":
+		if err := json.NewEncoder(os.Stdout).Encode(results); err != nil {
+			log.Fatal("Failed to write json: ", err)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package leaf
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVImport configures conversion of CSV rows into cards. Columns
+// are referenced by a 1-based index or by a name from the header
+// row, empty ID and Tags columns are not imported. Answer columns can
+// be referenced using a name pattern, e.g. "side_*".
+type CSVImport struct {
+	Comma    rune
+	Header   bool
+	ID       string
+	Question string
+	Answers  []string
+	Tags     string
+	// Properties are written into the property drawer of the deck.
+	Properties [][2]string
+}
+
+// ImportCSV converts CSV rows into an org deck with a given name and
+// returns amount of imported cards. Each answer column defines a
+// side, multi-line cells define a side per line. Multi-line
+// questions use the first line as a headline. Rows without a
+// question or answers are skipped, cards without ID are assigned a
+// generated one.
+func ImportCSV(r io.Reader, w io.Writer, deckName string, opts CSVImport) (int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("csv: %s", err)
+	}
+
+	var header []string
+	if opts.Header && len(rows) > 0 {
+		header, rows = rows[0], rows[1:]
+	}
+
+	idCol, err := csvColumn(opts.ID, header)
+	if err != nil {
+		return 0, err
+	}
+	questionCol, err := csvColumn(opts.Question, header)
+	if err != nil {
+		return 0, err
+	}
+	if questionCol < 0 {
+		return 0, fmt.Errorf("csv: missing question column")
+	}
+	tagsCol, err := csvColumn(opts.Tags, header)
+	if err != nil {
+		return 0, err
+	}
+
+	answerCols := make([]int, 0, len(opts.Answers))
+	for _, ref := range opts.Answers {
+		if strings.ContainsAny(ref, "*?[") {
+			for idx, name := range header {
+				if ok, _ := path.Match(ref, strings.TrimSpace(name)); ok {
+					answerCols = append(answerCols, idx)
+				}
+			}
+			continue
+		}
+
+		col, err := csvColumn(ref, header)
+		if err != nil {
+			return 0, err
+		}
+		if col >= 0 {
+			answerCols = append(answerCols, col)
+		}
+	}
+	if len(answerCols) == 0 {
+		return 0, fmt.Errorf("csv: missing answer columns")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("* " + deckName + "\n:PROPERTIES:\n")
+	for _, prop := range opts.Properties {
+		sb.WriteString(":" + prop[0] + ": " + prop[1] + "\n")
+	}
+	sb.WriteString(":END:\n")
+
+	count := 0
+	for idx, row := range rows {
+		cell := func(col int) string {
+			if col < 0 || col >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[col])
+		}
+
+		question := cell(questionCol)
+		var sides []string
+		for _, col := range answerCols {
+			for _, side := range strings.Split(cell(col), "\n") {
+				if side = strings.TrimSpace(side); side != "" {
+					sides = append(sides, side)
+				}
+			}
+		}
+		if question == "" || len(sides) == 0 {
+			continue
+		}
+
+		tags, err := csvTags(cell(tagsCol))
+		if err != nil {
+			return count, fmt.Errorf("csv: row %d: %s", idx+1, err)
+		}
+
+		id := cell(idCol)
+		if id == "" {
+			if id, err = newCardID(); err != nil {
+				return count, err
+			}
+		}
+
+		lines := strings.Split(question, "\n")
+		title := strings.TrimSpace(lines[0])
+		if len(tags) > 0 {
+			title += " :" + strings.Join(tags, ":") + ":"
+		}
+
+		body := append(lines[1:], sides...)
+		for _, line := range body {
+			if headlineRegexp.MatchString(line) {
+				return count, fmt.Errorf("csv: row %d: line %q starts a headline", idx+1, line)
+			}
+		}
+
+		sb.WriteString("** " + title + "\n:PROPERTIES:\n:ID: " + id + "\n:END:\n")
+		sb.WriteString(strings.Join(body, "\n") + "\n")
+		count++
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return count, fmt.Errorf("csv: %s", err)
+	}
+
+	return count, nil
+}
+
+// csvColumn returns index of a referenced column, -1 is returned
+// for an empty reference.
+func csvColumn(ref string, header []string) (int, error) {
+	if ref == "" {
+		return -1, nil
+	}
+
+	if idx, err := strconv.Atoi(ref); err == nil && idx > 0 {
+		return idx - 1, nil
+	}
+
+	for idx, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), ref) {
+			return idx, nil
+		}
+	}
+
+	return -1, fmt.Errorf("csv: unknown column %q", ref)
+}
+
+// csvTags returns tags of a cell separated by spaces, commas or
+// colons. Tags with characters that are not parsed in headlines are
+// rejected.
+func csvTags(cell string) ([]string, error) {
+	tags := strings.FieldsFunc(cell, func(r rune) bool {
+		return r == ',' || r == ':' || r == ' ' || r == '\t'
+	})
+
+	for _, tag := range tags {
+		for _, r := range tag {
+			if !isTagRune(r) {
+				return nil, fmt.Errorf("tags: invalid tag %q, only ASCII letters, digits and _@#%% are supported", tag)
+			}
+		}
+	}
+
+	return tags, nil
+}
+
+// ExportCSV writes cards of a deck or a sub-deck along with their
+// stats as CSV. Columns are id, question, a column per side, tags,
+// next review time and interval in days. Questions are exported in
+// the output format of the manager. Generated reversed and cloze
+// cards are not exported, cloze cards are exported as their source
+// card without stats.
+func (dm DeckManager) ExportCSV(deckName string, w io.Writer, comma rune) error {
+	deck, _ := dm.findDeck(deckName)
+	stats, err := dm.DeckStats(deckName)
+	if err != nil {
+		return err
+	}
+
+	type row struct {
+		card                   Card
+		nextReviewAt, interval string
+	}
+
+	rows := make([]row, 0, len(stats))
+	exported := make(map[string]bool, len(stats))
+	for _, s := range stats {
+		if source, ok := deck.sources[s.ID]; ok {
+			if !exported[source.ID] {
+				rows = append(rows, row{card: source})
+				exported[source.ID] = true
+			}
+			continue
+		}
+
+		nextReviewAt := ""
+		if t := s.NextReviewAt(); !t.IsZero() {
+			nextReviewAt = t.Format(time.RFC3339)
+		}
+		rows = append(rows, row{s.Card, nextReviewAt, strconv.FormatFloat(s.interval(), 'f', 2, 64)})
+		exported[s.ID] = true
+	}
+
+	sides := 1
+	for _, r := range rows {
+		if len(r.card.Sides) > sides {
+			sides = len(r.card.Sides)
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if comma != 0 {
+		writer.Comma = comma
+	}
+
+	header := []string{"id", "question"}
+	for idx := 1; idx <= sides; idx++ {
+		header = append(header, fmt.Sprintf("side_%d", idx))
+	}
+	header = append(header, "tags", "next_review_at", "interval")
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("csv: %s", err)
+	}
+
+	for _, r := range rows {
+		record := make([]string, 0, len(header))
+		record = append(record, r.card.ID, r.card.Question)
+		for idx := 0; idx < sides; idx++ {
+			side := ""
+			if idx < len(r.card.Sides) {
+				side = r.card.Sides[idx]
+			}
+			record = append(record, side)
+		}
+		record = append(record, strings.Join(r.card.Tags, " "), r.nextReviewAt, r.interval)
+
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("csv: %s", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("csv: %s", err)
+	}
+
+	return nil
+}
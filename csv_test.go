@@ -0,0 +1,191 @@
+package leaf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportCSV(t *testing.T) {
+	input := "word\treading\tmeaning\ttags\n" +
+		"犬\tいぬ\tdog\tanimals nouns\n" +
+		"猫\tねこ\t\"cat\nkitty\"\tanimals\n" +
+		"\t\tempty\t\n"
+
+	var out bytes.Buffer
+	count, err := ImportCSV(strings.NewReader(input), &out, "Vocabulary", CSVImport{
+		Comma:      '\t',
+		Header:     true,
+		Question:   "word",
+		Answers:    []string{"2", "meaning"},
+		Tags:       "tags",
+		Properties: [][2]string{{"RATER", "self"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "vocabulary.org")
+	require.NoError(t, ioutil.WriteFile(filename, out.Bytes(), 0644))
+
+	decks, err := OpenDeck(filename, OutputFormatOrg)
+	require.NoError(t, err)
+	require.Len(t, decks, 1)
+
+	deck := decks[0]
+	assert.Equal(t, "Vocabulary", deck.Name)
+	assert.Equal(t, RatingTypeSelf, deck.RatingType)
+	require.Len(t, deck.Cards, 2)
+	assert.NotEmpty(t, deck.Cards[0].ID)
+	assert.Equal(t, "犬", deck.Cards[0].Question)
+	assert.Equal(t, []string{"いぬ", "dog"}, deck.Cards[0].Sides)
+	assert.Equal(t, []string{"animals", "nouns"}, deck.Cards[0].Tags)
+	assert.Equal(t, []string{"ねこ", "cat", "kitty"}, deck.Cards[1].Sides)
+
+	_, err = ImportCSV(strings.NewReader(input), &out, "Vocabulary", CSVImport{Comma: '\t', Question: "word", Answers: []string{"2"}})
+	assert.EqualError(t, err, `csv: unknown column "word"`)
+
+	_, err = ImportCSV(strings.NewReader("foo,bar,baz!\n"), &out, "Vocabulary", CSVImport{Question: "1", Answers: []string{"2"}, Tags: "3"})
+	assert.EqualError(t, err, `csv: row 1: tags: invalid tag "baz!", only ASCII letters, digits and _@#% are supported`)
+
+	_, err = ImportCSV(strings.NewReader("犬,dog,nouns\n食べる,to eat,動詞\n"), &out, "Vocabulary", CSVImport{Question: "1", Answers: []string{"2"}, Tags: "3"})
+	assert.EqualError(t, err, `csv: row 2: tags: invalid tag "動詞", only ASCII letters, digits and _@#% are supported`)
+}
+
+func TestExportCSV(t *testing.T) {
+	fixtures := copyFixtures(t)
+	defer os.RemoveAll(fixtures)
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+	defer db.Close()
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	dm, err := NewDeckManager(DeckSource{Roots: []string{fixtures}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cards, err := dm.Cards("Hiragana")
+	require.NoError(t, err)
+
+	s := &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}
+	s.Advance(1)
+	require.NoError(t, db.SaveStats("Hiragana", cards[0].ID, s))
+
+	var exported bytes.Buffer
+	require.NoError(t, dm.ExportCSV("Hiragana", &exported, ','))
+
+	lines := strings.Split(exported.String(), "\n")
+	assert.Equal(t, "id,question,side_1,tags,next_review_at,interval", lines[0])
+	assert.Equal(t, cards[0].ID+",あ,a,,2019-01-01T09:00:00Z,0.38", lines[1])
+
+	for _, deckName := range []string{"Hiragana", "Org-mode"} {
+		t.Run(deckName, func(t *testing.T) {
+			cards, err := dm.Cards(deckName)
+			require.NoError(t, err)
+
+			var exported bytes.Buffer
+			require.NoError(t, dm.ExportCSV(deckName, &exported, ';'))
+
+			var imported bytes.Buffer
+			_, err = ImportCSV(&exported, &imported, deckName, CSVImport{
+				Header:   true,
+				ID:       "id",
+				Question: "question",
+				Comma:    ';',
+				Answers:  []string{"side_*"},
+				Tags:     "tags",
+			})
+			require.NoError(t, err)
+
+			filename := filepath.Join(dir, deckName+".org")
+			require.NoError(t, ioutil.WriteFile(filename, imported.Bytes(), 0644))
+
+			decks, err := OpenDeck(filename, OutputFormatOrg)
+			require.NoError(t, err)
+			require.Len(t, decks, 1)
+			assert.Equal(t, cards, decks[0].Cards)
+		})
+	}
+}
+
+func TestExportCSVGeneratedCards(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	decks := map[string]string{
+		"Reversed": "* Reversed\n:PROPERTIES:\n:REVERSE: t\n:END:\n** cat\n:PROPERTIES:\n:ID: cat\n:END:\nneko\n** dog\n:PROPERTIES:\n:ID: dog\n:END:\ninu\n",
+		"Cloze":    "* Cloze\n** Sentence\n:PROPERTIES:\n:ID: s\n:END:\n私は{{c1::学生}}です。\n{{c2::猫::animal}}が好き。\n** foo\n:PROPERTIES:\n:ID: foo\n:END:\nbar\n",
+	}
+	for name, content := range decks {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name+".org"), []byte(content), 0644))
+	}
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+	defer db.Close()
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	dm, err := NewDeckManager(DeckSource{Roots: []string{dir}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	var exported bytes.Buffer
+	require.NoError(t, dm.ExportCSV("Cloze", &exported, ','))
+	assert.Equal(
+		t,
+		"id,question,side_1,side_2,tags,next_review_at,interval\n"+
+			"s,Sentence,私は{{c1::学生}}です。,{{c2::猫::animal}}が好き。,,,\n"+
+			"foo,foo,bar,,,2019-01-01T00:00:00Z,0.20\n",
+		exported.String(),
+	)
+
+	for name, properties := range map[string][][2]string{"Reversed": {{"REVERSE", "t"}}, "Cloze": nil} {
+		t.Run(name, func(t *testing.T) {
+			cards, err := dm.Cards(name)
+			require.NoError(t, err)
+
+			var exported bytes.Buffer
+			require.NoError(t, dm.ExportCSV(name, &exported, ','))
+
+			var imported bytes.Buffer
+			_, err = ImportCSV(&exported, &imported, name, CSVImport{
+				Header:     true,
+				ID:         "id",
+				Question:   "question",
+				Answers:    []string{"side_*"},
+				Properties: properties,
+			})
+			require.NoError(t, err)
+
+			filename := filepath.Join(dir, "imported.org")
+			require.NoError(t, ioutil.WriteFile(filename, imported.Bytes(), 0644))
+
+			decks, err := OpenDeck(filename, OutputFormatOrg)
+			require.NoError(t, err)
+			require.Len(t, decks, 1)
+			assert.Equal(t, cards, decks[0].Cards)
+		})
+	}
+}
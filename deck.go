@@ -1,8 +1,13 @@
 package leaf
 
 import (
+	"crypto/rand"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -21,11 +26,15 @@ const (
 )
 
 // Card represents a single card in a Deck. Each card may have
-// multiple sides (answers).
+// multiple sides (answers). Cards are identified by an ID property
+// of the headline, stats are stored using it.
 type Card struct {
+	ID          string   `json:"id"`
 	Question    string   `json:"card"`
 	RawQuestion string   `json:"raw_card"`
 	Sides       []string `json:"-"`
+	SubDeck     string   `json:"sub_deck,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
 }
 
 // Answer returns combined space separated answer for all sides of the card.
@@ -33,41 +42,98 @@ func (c Card) Answer() string {
 	return strings.Join(c.Sides, " ")
 }
 
-// Deck represents a named collection of the cards to review.
+// SubDeck is a named group of cards within a Deck. Sub-decks are
+// defined by second level headlines of decks with SUBDECKS property,
+// their properties are inherited from the deck.
+type SubDeck struct {
+	Name       string
+	Tags       []string
+	Params     SRSParams
+	RatingType RatingType
+	PerReview  int
+	Reverse    bool
+}
+
+// DuplicateCards describes cards of a deck that share a question.
+// Such cards are reviewed separately and keep separate stats under
+// their IDs.
+type DuplicateCards struct {
+	Deck     string   `json:"deck"`
+	Question string   `json:"question"`
+	Cards    []string `json:"cards"`
+}
+
+func (d DuplicateCards) String() string {
+	return fmt.Sprintf("deck %s: cards %s share question %q", d.Deck, strings.Join(d.Cards, ", "), d.Question)
+}
+
+// Deck represents a named collection of the cards to review. Name
+// of a deck is prefixed with a Namespace if deck has one.
 type Deck struct {
 	Name       string
+	Namespace  string
 	Cards      []Card
+	SubDecks   []SubDeck
+	Tags       []string
 	Algorithm  SRS
+	Params     SRSParams
 	RatingType RatingType
 	PerReview  int
+	Reverse    bool
+	// Duplicates lists cards with identical questions.
+	Duplicates []DuplicateCards
 
 	format   OutputFormat
 	modtime  time.Time
 	filename string
+	title    string
+	// index is a document index of the deck headline.
+	index int
+	// legacyKeys maps questions rendered in all output formats to
+	// card IDs, such keys were used for stats by older versions.
+	legacyKeys map[string]string
+	// sources maps IDs of generated reversed and cloze cards to cards
+	// defined by their headlines, sides of cloze sources contain
+	// text with deletions.
+	sources map[string]Card
+	// pending maps headline indexes to generated IDs that are not
+	// written into the file yet.
+	pending map[int]string
 }
 
-// OpenDeck loads deck from an org file. File format is:
+// OpenDeck loads decks from an org file, each top level headline
+// defines a separate deck. File format is:
 // * Deck Name
 // ** Question
 // side 1
 // side 2
-func OpenDeck(filename string, format OutputFormat) (*Deck, error) {
-	f, err := os.Open(filename)
-	if err != nil {
-		return nil, fmt.Errorf("file: %s", err)
-	}
+// Files with .md or .markdown extension are parsed as Markdown
+// using "#" headings, see markdownToOrg. Cards without ID property
+// are assigned a generated one, file is not modified until WriteIDs
+// is called.
+func OpenDeck(filename string, format OutputFormat) ([]*Deck, error) {
+	return openDecks(filename, "", format)
+}
 
-	stat, err := f.Stat()
+// openDecks loads decks from an org or Markdown file using provided
+// namespace.
+func openDecks(filename, namespace string, format OutputFormat) ([]*Deck, error) {
+	roots, modtime, err := parseFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("file: %s", err)
+		return nil, err
 	}
 
-	deck := &Deck{modtime: stat.ModTime(), filename: filename, format: format}
-	if err := deck.load(f); err != nil {
-		return nil, err
+	decks := make([]*Deck, 0, len(roots))
+	for _, root := range roots {
+		deck := &Deck{Namespace: namespace, filename: filename, format: format, modtime: modtime}
+		if deck.pending, err = deck.load(root); err != nil {
+			return nil, err
+		}
+
+		decks = append(decks, deck)
 	}
 
-	return deck, nil
+	return decks, nil
 }
 
 // Reload compares ModTime on deck file and reloads cards if necessary.
@@ -81,35 +147,259 @@ func (deck *Deck) Reload() error {
 		return nil
 	}
 
-	f, err := os.Open(deck.filename)
+	return deck.open()
+}
+
+// SetParams writes provided parameters into property drawer of the
+// deck and reloads it. Existing properties are updated in place,
+// missing ones are appended to the drawer. Drawer is created if deck
+// doesn't have one.
+func (deck *Deck) SetParams(params SRSParams) error {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	syntax := syntaxOf(deck.filename)
+	err := rewriteFile(deck.filename, func(lines []string) ([]string, error) {
+		headlines := syntax.headlines(lines)
+		if len(headlines) < deck.index {
+			return nil, fmt.Errorf("deck %s is not found in %s", deck.Name, deck.filename)
+		}
+
+		for _, name := range names {
+			lines = syntax.setProperty(lines, headlines[deck.index-1], name, params[name])
+		}
+
+		return lines, nil
+	})
 	if err != nil {
-		return fmt.Errorf("file: %s", err)
+		return err
 	}
 
-	if err := deck.load(f); err != nil {
+	return deck.open()
+}
+
+// open loads deck from its file. Deck is looked up by the headline
+// title, files with a single deck are loaded regardless of the
+// title.
+func (deck *Deck) open() error {
+	roots, modtime, err := parseFile(deck.filename)
+	if err != nil {
 		return err
 	}
 
-	deck.modtime = stat.ModTime()
+	var root *org.Headline
+	for idx := range roots {
+		if org.String(roots[idx].Title) == deck.title || len(roots) == 1 {
+			root = &roots[idx]
+			break
+		}
+	}
+	if root == nil {
+		return fmt.Errorf("deck %s is not found in %s", deck.Name, deck.filename)
+	}
+
+	if deck.pending, err = deck.load(*root); err != nil {
+		return err
+	}
+
+	deck.modtime = modtime
+	return nil
+}
+
+// WriteIDs writes generated IDs of cards without ID property into the
+// deck file, so that stats of such cards can be saved. Deck is
+// reloaded first if the file was modified.
+func (deck *Deck) WriteIDs() error {
+	if err := deck.Reload(); err != nil {
+		return err
+	}
+
+	if len(deck.pending) == 0 {
+		return nil
+	}
+
+	modtime, err := writeIDs(deck.filename, deck.pending)
+	if err != nil {
+		return err
+	}
+
+	deck.pending, deck.modtime = nil, modtime
 	return nil
 }
 
-func (deck *Deck) load(f *os.File) error {
-	doc := org.New().Parse(f, "./")
+// parseFile returns top level headlines of an org or Markdown file
+// along with its modification time.
+func parseFile(filename string) ([]org.Headline, time.Time, error) {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("file: %s", err)
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("file: %s", err)
+	}
+
+	text := string(content)
+	if isMarkdown(filename) {
+		text = markdownToOrg(text)
+	} else {
+		text = stripPlanning(text)
+	}
+
+	doc := org.New().Parse(strings.NewReader(text), "./")
 	if len(doc.Nodes) == 0 {
-		return fmt.Errorf("empty or invalid org-file")
+		return nil, time.Time{}, fmt.Errorf("empty or invalid org-file")
 	}
 
-	root, ok := doc.Nodes[0].(org.Headline)
-	if !ok {
-		return fmt.Errorf("org-file doesn't start with a headline")
+	if _, ok := doc.Nodes[0].(org.Headline); !ok {
+		return nil, time.Time{}, fmt.Errorf("org-file doesn't start with a headline")
+	}
+
+	roots := make([]org.Headline, 0, len(doc.Nodes))
+	for _, node := range doc.Nodes {
+		if headline, ok := node.(org.Headline); ok {
+			roots = append(roots, headline)
+		}
+	}
+
+	return roots, stat.ModTime(), nil
+}
+
+// fileSyntax locates headlines and edits their properties in lines
+// of a deck file.
+type fileSyntax struct {
+	headlines   func(lines []string) []int
+	setProperty func(lines []string, headline int, name, value string) []string
+}
+
+// syntaxOf returns syntax of a deck file based on its extension.
+func syntaxOf(filename string) fileSyntax {
+	if isMarkdown(filename) {
+		return fileSyntax{markdownHeadlineLines, setMarkdownProperty}
+	}
+
+	return fileSyntax{headlineLines, setProperty}
+}
+
+// writeIDs writes card IDs keyed by headline index into a deck file
+// and returns its new modification time.
+func writeIDs(filename string, ids map[int]string) (time.Time, error) {
+	syntax := syntaxOf(filename)
+	err := rewriteFile(filename, func(lines []string) ([]string, error) {
+		headlines := syntax.headlines(lines)
+		// insert from the bottom to keep line numbers of other headlines
+		for idx := len(headlines); idx > 0; idx-- {
+			if id, ok := ids[idx]; ok {
+				lines = syntax.setProperty(lines, headlines[idx-1], "ID", id)
+			}
+		}
+
+		return lines, nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("file: %s", err)
+	}
+
+	return stat.ModTime(), nil
+}
+
+// rewriteFile applies an edit to lines of a file. Lines are passed
+// without line endings, line ending of the first line is used for
+// all lines of the edited file.
+func rewriteFile(filename string, edit func(lines []string) ([]string, error)) error {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("file: %s", err)
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("file: %s", err)
+	}
+
+	text, eol := string(content), "\n"
+	if idx := strings.Index(text, "\n"); idx > 0 && text[idx-1] == '\r' {
+		text, eol = strings.Replace(text, "\r\n", "\n", -1), "\r\n"
+	}
+
+	lines, err := edit(strings.Split(text, "\n"))
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, []byte(strings.Join(lines, eol)), stat.Mode())
+}
+
+// writeFileAtomic replaces content of a file by writing a temporary
+// file next to it and renaming it, so the file is never left
+// partially written. Symlinks are resolved to keep them intact.
+func writeFileAtomic(filename string, data []byte, mode os.FileMode) error {
+	path, err := filepath.EvalSymlinks(filename)
+	if err != nil {
+		return fmt.Errorf("file: %s", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return fmt.Errorf("file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("file: %s", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("file: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("file: %s", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("file: %s", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("file: %s", err)
+	}
+
+	return nil
+}
+
+// load parses deck from a top level headline. Cards without ID are
+// assigned a generated one, returned map contains such IDs keyed by
+// the headline index.
+func (deck *Deck) load(root org.Headline) (map[int]string, error) {
+	deck.title = org.String(root.Title)
+	deck.index = root.Index
+	deck.Name = deck.title
+	if deck.Namespace != "" {
+		deck.Name = deck.Namespace + "/" + deck.Name
 	}
-	deck.Name = org.String(root.Title)
 	deck.Cards = make([]Card, 0, len(root.Children))
+	deck.SubDecks = make([]SubDeck, 0)
+	deck.Tags = root.Tags
 	deck.Algorithm = SRSSupermemo2PlusCustom
+	deck.Params = make(SRSParams)
 	deck.RatingType = RatingTypeAuto
 	deck.PerReview = 20
+	deck.Reverse = false
+	nested := false
 	if root.Properties != nil {
+		for _, prop := range root.Properties.Properties {
+			deck.Params[prop[0]] = prop[1]
+		}
+
 		if rater, success := root.Properties.Get("RATER"); success {
 			deck.RatingType = RatingType(rater)
 		}
@@ -121,34 +411,370 @@ func (deck *Deck) load(f *os.File) error {
 				deck.PerReview = c
 			}
 		}
+		if value, success := root.Properties.Get("SUBDECKS"); success {
+			nested, _ = strconv.ParseBool(value)
+		}
+		if value, success := root.Properties.Get("REVERSE"); success {
+			deck.Reverse, _ = strconv.ParseBool(value)
+		}
+	}
+
+	if _, err := NewStats(deck.Algorithm, SystemClock, deck.Params); err != nil {
+		return nil, err
 	}
 
+	ids := make(map[int]string)
+	deck.legacyKeys = make(map[string]string)
+	deck.sources = make(map[string]Card)
 	for _, node := range root.Children {
 		headline, ok := node.(org.Headline)
-		if !ok || len(headline.Children) == 0 {
+		if !ok {
+			continue
+		}
+
+		if !nested {
+			if err := deck.addCard(headline, "", deck.Tags, ids); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
-		var w org.Writer
-		if deck.format == OutputFormatHTML {
-			w = org.NewHTMLWriter()
-		} else {
-			w = org.NewOrgWriter()
+		sub, err := deck.newSubDeck(headline)
+		if err != nil {
+			return nil, err
+		}
+		deck.SubDecks = append(deck.SubDecks, sub)
+
+		for _, child := range headline.Children {
+			if cardHeadline, ok := child.(org.Headline); ok {
+				if err := deck.addCard(cardHeadline, sub.Name, sub.Tags, ids); err != nil {
+					return nil, err
+				}
+			}
 		}
+	}
 
-		org.WriteNodes(w, headline.Title...)
+	deck.Duplicates = duplicateCards(deck.Name, deck.Cards)
+	return ids, nil
+}
 
-		var answers string
-		if block, ok := headline.Children[0].(org.Block); ok && block.Name == "SRC" {
-			org.WriteNodes(w, block)
-			answers = strings.TrimSpace(org.String(headline.Children[1:]))
-		} else {
-			answers = strings.TrimSpace(org.String(headline.Children))
+// duplicateCards returns groups of cards with identical questions in
+// order of the first card of each group.
+func duplicateCards(deckName string, cards []Card) []DuplicateCards {
+	groups := make(map[string]int)
+	result := make([]DuplicateCards, 0)
+	seen := make(map[string]string)
+	for _, card := range cards {
+		first, ok := seen[card.Question]
+		if !ok {
+			seen[card.Question] = card.ID
+			continue
 		}
 
-		card := Card{w.String(), org.String(headline.Title), strings.Split(answers, "\n")}
-		deck.Cards = append(deck.Cards, card)
+		idx, ok := groups[card.Question]
+		if !ok {
+			idx = len(result)
+			groups[card.Question] = idx
+			result = append(result, DuplicateCards{deckName, card.Question, []string{first}})
+		}
+		result[idx].Cards = append(result[idx].Cards, card.ID)
+	}
+
+	return result
+}
+
+// newSubDeck returns a sub-deck defined by a headline, sub-deck
+// properties override inherited deck properties.
+func (deck *Deck) newSubDeck(headline org.Headline) (SubDeck, error) {
+	sub := SubDeck{
+		Name:       org.String(headline.Title),
+		Tags:       mergeTags(deck.Tags, headline.Tags),
+		Params:     make(SRSParams, len(deck.Params)),
+		RatingType: deck.RatingType,
+		PerReview:  deck.PerReview,
+		Reverse:    deck.Reverse,
+	}
+	for name, value := range deck.Params {
+		sub.Params[name] = value
+	}
+
+	if headline.Properties == nil {
+		return sub, nil
+	}
+
+	if _, ok := headline.Properties.Get("ALGORITHM"); ok {
+		return sub, fmt.Errorf("sub-deck %s/%s: ALGORITHM can only be defined for a deck", deck.Name, sub.Name)
+	}
+
+	for _, prop := range headline.Properties.Properties {
+		if prop[0] != "ID" {
+			sub.Params[prop[0]] = prop[1]
+		}
+	}
+
+	if rater, success := headline.Properties.Get("RATER"); success {
+		sub.RatingType = RatingType(rater)
+	}
+	if count, success := headline.Properties.Get("PER_REVIEW"); success {
+		if c, err := strconv.Atoi(count); err == nil {
+			sub.PerReview = c
+		}
+	}
+	if value, success := headline.Properties.Get("REVERSE"); success {
+		sub.Reverse, _ = strconv.ParseBool(value)
+	}
+
+	if _, err := NewStats(deck.Algorithm, SystemClock, sub.Params); err != nil {
+		return sub, err
+	}
+
+	return sub, nil
+}
+
+// addCard appends a card defined by a headline to the deck,
+// headlines without answers are skipped. Headlines with cloze
+// deletions in the body define a card per deletion number, headline
+// of such cards is only a label and isn't a part of the question
+// (e.g. titles of imported Anki cloze notes reveal deletions).
+// Reversed decks get a reversed card for each headline. Card tags
+// include provided inherited tags. Generated IDs are added to ids
+// keyed by the headline index.
+func (deck *Deck) addCard(headline org.Headline, subDeck string, tags []string, ids map[int]string) error {
+	if len(headline.Children) == 0 {
+		return nil
+	}
+
+	nodes := headline.Title
+	var answers, srcBlock string
+	if block, ok := headline.Children[0].(org.Block); ok && block.Name == "SRC" {
+		nodes = append(append([]org.Node{}, nodes...), block)
+		answers = strings.TrimSpace(org.String(headline.Children[1:]))
+		srcBlock = strings.TrimSpace(org.String([]org.Node{block}))
+	} else {
+		answers = strings.TrimSpace(org.String(headline.Children))
+	}
+
+	orgQuestion, htmlQuestion := org.NewOrgWriter(), org.NewHTMLWriter()
+	org.WriteNodes(orgQuestion, nodes...)
+	org.WriteNodes(htmlQuestion, nodes...)
+
+	// source blocks are kept on separate lines of org questions
+	question := org.String(headline.Title)
+	if srcBlock != "" {
+		question += "\n" + srcBlock
+	}
+	if deck.format == OutputFormatHTML {
+		question = htmlQuestion.String()
+	}
+
+	id, ok := headline.Properties.Get("ID")
+	if !ok || id == "" {
+		var err error
+		if id, err = newCardID(); err != nil {
+			return err
+		}
+		ids[headline.Index] = id
+	}
+
+	body := strings.TrimSpace(org.String(headline.Children))
+	if clozes := parseClozes(body); clozes != nil {
+		title := org.String(headline.Title)
+		source := Card{id, renderOrg(title, deck.format), title, strings.Split(body, "\n"), subDeck, mergeTags(tags, headline.Tags)}
+		for _, c := range clozes {
+			card := Card{clozeCardID(id, c.number), renderOrg(c.question, deck.format), c.question, c.answers, subDeck, mergeTags(tags, headline.Tags)}
+			deck.Cards = append(deck.Cards, card)
+			deck.sources[card.ID] = source
+		}
+		return nil
+	}
+
+	card := Card{id, question, org.String(headline.Title), strings.Split(answers, "\n"), subDeck, mergeTags(tags, headline.Tags)}
+	deck.Cards = append(deck.Cards, card)
+	// stats saved by question belong to the first of duplicated cards
+	for _, key := range []string{card.RawQuestion, question, orgQuestion.String(), htmlQuestion.String()} {
+		if _, ok := deck.legacyKeys[key]; !ok {
+			deck.legacyKeys[key] = id
+		}
+	}
+
+	reverse := deck.Reverse
+	if sub := deck.SubDeck(subDeck); sub != nil {
+		reverse = sub.Reverse
+	}
+	if value, ok := headline.Properties.Get("REVERSE"); ok {
+		reverse, _ = strconv.ParseBool(value)
+	}
+
+	if reverse {
+		reversed := card.reversed(deck.format)
+		deck.Cards = append(deck.Cards, reversed)
+		deck.sources[reversed.ID] = card
 	}
 
 	return nil
 }
+
+// reversed returns a card that uses sides of the card as a question
+// and raw question as an answer. Sides are rendered on separate
+// lines, reversed card has a separate ID.
+func (c Card) reversed(format OutputFormat) Card {
+	question := strings.Join(c.Sides, "\n")
+	rendered := question
+	if format == OutputFormatHTML {
+		rendered = renderOrg(strings.Join(c.Sides, "\n\n"), format)
+	}
+
+	return Card{reversedCardID(c.ID), rendered, question, []string{c.RawQuestion}, c.SubDeck, c.Tags}
+}
+
+// reversedCardID returns ID of a reversed card for a card ID.
+func reversedCardID(id string) string {
+	return id + "#r"
+}
+
+// SubDeck returns a sub-deck with a given name.
+func (deck *Deck) SubDeck(name string) *SubDeck {
+	for idx := range deck.SubDecks {
+		if deck.SubDecks[idx].Name == name {
+			return &deck.SubDecks[idx]
+		}
+	}
+
+	return nil
+}
+
+// cardParams returns algorithm parameters of a card, sub-deck cards
+// use parameters of their sub-deck.
+func (deck *Deck) cardParams(card Card) SRSParams {
+	if sub := deck.SubDeck(card.SubDeck); sub != nil {
+		return sub.Params
+	}
+
+	return deck.Params
+}
+
+// newCardID returns a random UUID similar to org-id.
+func newCardID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("id: %s", err)
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+var (
+	headlineRegexp   = regexp.MustCompile(`^\*+\s`)
+	beginBlockRegexp = regexp.MustCompile(`(?i)^\s*#\+BEGIN_(\w+)`)
+	endBlockRegexp   = regexp.MustCompile(`(?i)^\s*#\+END_(\w+)`)
+	planningRegexp   = regexp.MustCompile(`^\s*(SCHEDULED|DEADLINE|CLOSED):`)
+)
+
+// headlineLines returns line numbers of headlines in org file lines
+// in the order they are indexed by the parser. Same as the parser
+// only source, example and export blocks with a matching end line
+// hide headlines.
+func headlineLines(lines []string) []int {
+	result := make([]int, 0)
+	for idx := 0; idx < len(lines); idx++ {
+		if m := beginBlockRegexp.FindStringSubmatch(lines[idx]); m != nil && isRawBlock(m[1]) {
+			if end := blockEnd(lines, idx, m[1]); end != -1 {
+				idx = end
+				continue
+			}
+		}
+
+		if headlineRegexp.MatchString(lines[idx]) {
+			result = append(result, idx)
+		}
+	}
+
+	return result
+}
+
+// isRawBlock reports whether content of a block with a given name is
+// not parsed.
+func isRawBlock(name string) bool {
+	switch strings.ToUpper(name) {
+	case "SRC", "EXAMPLE", "EXPORT":
+		return true
+	}
+
+	return false
+}
+
+// blockEnd returns index of a line closing a block that starts at a
+// given line, -1 is returned for unterminated blocks.
+func blockEnd(lines []string, start int, name string) int {
+	for idx := start + 1; idx < len(lines); idx++ {
+		if m := endBlockRegexp.FindStringSubmatch(lines[idx]); m != nil && strings.EqualFold(m[1], name) {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+// stripPlanning removes planning lines (SCHEDULED, DEADLINE and
+// CLOSED) that follow headlines, parser only reads property drawers
+// placed right after a headline and planning is not a part of answers.
+func stripPlanning(text string) string {
+	lines := strings.Split(text, "\n")
+	headlines := headlineLines(lines)
+	for idx := len(headlines) - 1; idx >= 0; idx-- {
+		next := headlines[idx] + 1
+		if next < len(lines) && planningRegexp.MatchString(lines[next]) {
+			lines = append(lines[:next], lines[next+1:]...)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// setProperty sets a property of a headline at a given line.
+// Existing property is updated in place keeping alignment of the
+// value, otherwise property is appended to the drawer. Drawer is
+// created if headline doesn't have one, it's placed after a planning
+// line of the headline.
+func setProperty(lines []string, headline int, name, value string) []string {
+	prefix := ":" + name + ":"
+	drawer := headline + 1
+	if drawer < len(lines) && planningRegexp.MatchString(lines[drawer]) {
+		drawer++
+	}
+
+	end := -1
+	if drawer < len(lines) && strings.EqualFold(strings.TrimSpace(lines[drawer]), ":PROPERTIES:") {
+		for idx := drawer + 1; idx < len(lines); idx++ {
+			line := strings.TrimLeft(lines[idx], " \t")
+			if strings.EqualFold(strings.TrimSpace(line), ":END:") {
+				end = idx
+				break
+			}
+
+			if !strings.HasPrefix(strings.ToUpper(line), prefix) {
+				continue
+			}
+
+			pos := len(prefix)
+			for pos < len(line) && (line[pos] == ' ' || line[pos] == '\t') {
+				pos++
+			}
+			if pos == len(prefix) {
+				lines[idx] = prefix + " " + value
+			} else {
+				lines[idx] = line[:pos] + value
+			}
+			return lines
+		}
+	}
+
+	if end == -1 {
+		lines = append(lines[:drawer], append([]string{":PROPERTIES:", ":END:"}, lines[drawer:]...)...)
+		end = drawer + 1
+	}
+
+	return append(lines[:end], append([]string{prefix + " " + value}, lines[end:]...)...)
+}
@@ -1,9 +1,14 @@
 package leaf
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -15,139 +20,1075 @@ type DeckStats struct {
 	Name         string    `json:"name"`
 	CardsReady   int       `json:"cards_ready"`
 	NextReviewAt time.Time `json:"next_review_at"`
+	// Inconsistent is an amount of cards that have stats saved with
+	// a different algorithm, such cards are not reviewed.
+	Inconsistent int `json:"inconsistent,omitempty"`
+}
+
+// StatsRepair describes duplicate stats records merged for a single card.
+type StatsRepair struct {
+	Card     string   `json:"card"`
+	Question string   `json:"question"`
+	Merged   []string `json:"merged"`
+	Stats    *Stats   `json:"stats"`
+}
+
+// OrphanedStats describes stats record without a matching card.
+// Match is set to a card that record can be re-linked to.
+type OrphanedStats struct {
+	Deck       string  `json:"deck"`
+	Card       string  `json:"card"`
+	MatchDeck  string  `json:"match_deck,omitempty"`
+	Match      string  `json:"match,omitempty"`
+	Question   string  `json:"question,omitempty"`
+	Similarity float64 `json:"similarity,omitempty"`
+	// Algorithm is an algorithm of the record, it's empty for
+	// untagged records of unknown decks.
+	Algorithm SRS    `json:"algorithm,omitempty"`
+	Stats     *Stats `json:"stats"`
+}
+
+// orphanMatchThreshold is a minimum similarity of questions to
+// suggest re-linking of an orphaned record.
+const orphanMatchThreshold = 0.6
+
+// StatsMigration describes stats conversion for a single card.
+type StatsMigration struct {
+	Card             string    `json:"card"`
+	NextReviewAt     time.Time `json:"next_review_at"`
+	MigratedReviewAt time.Time `json:"migrated_review_at"`
+	Stats            *Stats    `json:"stats"`
 }
 
 // DeckManager manages set of decks.
 type DeckManager struct {
 	db    StatsStore
 	decks []*Deck
+	clock Clock
+}
+
+// DeckSource defines location of deck files. Decks are discovered
+// recursively in each root, folders starting with a dot are skipped.
+// Include and Exclude are glob patterns matched against file path
+// relative to a root, "**" matches any amount of folders and
+// patterns without a slash are matched against a file name. All org
+// and Markdown files are included if Include is empty.
+type DeckSource struct {
+	Roots   []string
+	Include []string
+	Exclude []string
+}
+
+// deckFile is a deck file found in a DeckSource.
+type deckFile struct {
+	path      string
+	namespace string
 }
 
 // NewDeckManager constructs a new DeckManager by reading all decks
-// from a given folder using provided store.
-func NewDeckManager(path string, db StatsStore, outFormat OutputFormat) (*DeckManager, error) {
-	files, err := filepath.Glob(path + "/*.org")
+// from a given source using provided store. A single file may
+// define multiple decks. Decks from nested
+// folders are namespaced with a relative folder path, e.g.
+// languages/japanese/Hiragana. Reviews are scheduled using provided
+// clock. Deck files and stats are not modified, see WriteIDs.
+func NewDeckManager(source DeckSource, db StatsStore, outFormat OutputFormat, clock Clock) (*DeckManager, error) {
+	files, err := source.files()
 	if err != nil {
 		return nil, err
 	}
 
 	decks := make([]*Deck, 0, len(files))
+	paths := make(map[string]string, len(files))
 	for _, file := range files {
-		deck, err := OpenDeck(file, outFormat)
+		fileDecks, err := openDecks(file.path, file.namespace, outFormat)
 		if err != nil {
 			return nil, err
 		}
-		decks = append(decks, deck)
+
+		for _, deck := range fileDecks {
+			if path, ok := paths[deck.Name]; ok {
+				return nil, fmt.Errorf("deck %s is defined in %s and %s", deck.Name, path, file.path)
+			}
+			paths[deck.Name] = file.path
+			decks = append(decks, deck)
+		}
 	}
 
-	return &DeckManager{db, decks}, nil
+	return &DeckManager{db, decks, clock}, nil
 }
 
-// ReviewDecks returns stats for available decks.
+// files returns deck files of all roots in lexical order.
+func (source DeckSource) files() ([]deckFile, error) {
+	for _, pattern := range append(append([]string{}, source.Include...), source.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("deck: invalid pattern %q", pattern)
+		}
+	}
+
+	result := make([]deckFile, 0)
+	for _, root := range source.Roots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				if path != root && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if filepath.Ext(path) != ".org" && !isMarkdown(path) {
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+
+			if len(source.Include) > 0 && !matchAny(source.Include, rel) {
+				return nil
+			}
+			if matchAny(source.Exclude, rel) {
+				return nil
+			}
+
+			namespace := filepath.ToSlash(filepath.Dir(rel))
+			if namespace == "." {
+				namespace = ""
+			}
+
+			result = append(result, deckFile{path, namespace})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("file: %s", err)
+		}
+	}
+
+	return result, nil
+}
+
+// matchAny reports whether a slash separated path matches any of
+// the patterns.
+func matchAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+				return true
+			}
+			continue
+		}
+
+		if matchSegments(strings.Split(pattern, "/"), strings.Split(path, "/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchSegments matches path segments against pattern segments,
+// "**" segment matches any amount of path segments.
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+
+	if pattern[0] == "**" {
+		for idx := 0; idx <= len(path); idx++ {
+			if matchSegments(pattern[1:], path[idx:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(path) == 0 {
+		return false
+	}
+
+	if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+		return false
+	}
+
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// WriteIDs writes generated card IDs of all decks into their files
+// and moves stats and reviews stored by older versions using
+// questions of cards to card IDs. Operations that save stats do it
+// for their decks.
+func (dm DeckManager) WriteIDs() error {
+	for _, deck := range dm.decks {
+		if err := dm.persist(deck); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// persist writes generated card IDs of a deck into its file and
+// moves legacy records to card IDs, so that saved stats belong to
+// IDs kept in the file.
+func (dm DeckManager) persist(deck *Deck) error {
+	if err := deck.WriteIDs(); err != nil {
+		return err
+	}
+
+	if len(deck.legacyKeys) == 0 {
+		return nil
+	}
+
+	return dm.db.RenameLegacyCards(deck.Name, deck.legacyKeys)
+}
+
+// ReviewDecks returns stats for available decks. Decks with
+// sub-decks are followed by stats of each sub-deck, deck stats
+// include cards of all sub-decks.
 func (dm DeckManager) ReviewDecks() ([]DeckStats, error) {
 	result := make([]DeckStats, 0, len(dm.decks))
 	for _, deck := range dm.decks {
-		nextReviewAt, reviewDeck, err := dm.reviewDeck(deck, -1)
+		if err := deck.Reload(); err != nil {
+			return nil, err
+		}
+
+		stats, inconsistent, err := dm.deckStats(deck)
 		if err != nil {
 			return nil, err
 		}
 
-		result = append(result, DeckStats{deck.Name, len(reviewDeck), nextReviewAt})
+		nextReviewAt, cards := dm.readyCards(stats, "", -1)
+		result = append(result, DeckStats{deck.Name, len(cards), nextReviewAt, len(inconsistent)})
+
+		for _, sub := range deck.SubDecks {
+			subInconsistent := 0
+			for _, card := range deck.Cards {
+				if _, ok := inconsistent[card.ID]; ok && card.SubDeck == sub.Name {
+					subInconsistent++
+				}
+			}
+
+			nextReviewAt, cards := dm.readyCards(stats, sub.Name, -1)
+			result = append(result, DeckStats{deck.Name + "/" + sub.Name, len(cards), nextReviewAt, subInconsistent})
+		}
 	}
 
 	return result, nil
 }
 
 // ReviewSession initiates a new ReviewSession for a given deck name.
-func (dm DeckManager) ReviewSession(deckName string) (*ReviewSession, error) {
-	var deck *Deck
-	for _, d := range dm.decks {
-		if d.Name == deckName {
-			deck = d
-			break
-		}
+// Sub-decks are reviewed using their path, e.g. Hiragana/K-row.
+// Reviewed cards can be limited using a tag filter expression, see
+// ParseTagFilter.
+func (dm DeckManager) ReviewSession(deckName string, tags string) (*ReviewSession, error) {
+	deck, subName := dm.findDeck(deckName)
+	if deck == nil {
+		return nil, ErrNotFound
+	}
+
+	filter, err := ParseTagFilter(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dm.persist(deck); err != nil {
+		return nil, err
+	}
+
+	perReview := deck.PerReview
+	if sub := deck.SubDeck(subName); sub != nil {
+		perReview = sub.PerReview
+	}
+
+	_, cards, err := dm.reviewDeck(deck, subName, filter, perReview)
+	if err != nil {
+		return nil, err
+	}
+
+	ratingType := deck.RatingType
+	if sub := deck.SubDeck(subName); sub != nil {
+		ratingType = sub.RatingType
 	}
 
+	return NewReviewSession(
+		cards,
+		ratingType,
+		func(card *CardWithStats) error {
+			return dm.db.SaveStats(deck.Name, card.ID, card.Stats)
+		},
+		func(review *Review) error {
+			review.Deck = deck.Name
+			return dm.db.LogReview(review)
+		},
+		dm.clock,
+	), nil
+}
+
+// DeckStats returns card stats for a given deck or sub-deck name.
+func (dm DeckManager) DeckStats(deckName string) ([]CardWithStats, error) {
+	deck, subName := dm.findDeck(deckName)
 	if deck == nil {
 		return nil, ErrNotFound
 	}
 
-	_, cards, err := dm.reviewDeck(deck, deck.PerReview)
+	stats, _, err := dm.deckStats(deck)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewReviewSession(cards, deck.RatingType, func(card *CardWithStats) error {
-		return dm.db.SaveStats(deckName, card.Question, card.Stats)
-	}), nil
+	if subName == "" {
+		return stats, nil
+	}
+
+	result := make([]CardWithStats, 0, len(stats))
+	for _, s := range stats {
+		if s.SubDeck == subName {
+			result = append(result, s)
+		}
+	}
+
+	return result, nil
 }
 
-// DeckStats returns card stats for a given deck name.
-func (dm DeckManager) DeckStats(deckName string) ([]CardWithStats, error) {
-	var deck *Deck
-	for _, d := range dm.decks {
-		if d.Name == deckName {
-			deck = d
-			break
+// findDeck returns a deck for a given name along with a sub-deck
+// name if a path of a sub-deck was provided.
+func (dm DeckManager) findDeck(deckName string) (*Deck, string) {
+	for _, deck := range dm.decks {
+		if deck.Name == deckName {
+			return deck, ""
 		}
 	}
 
+	for _, deck := range dm.decks {
+		if !strings.HasPrefix(deckName, deck.Name+"/") {
+			continue
+		}
+
+		subName := strings.TrimPrefix(deckName, deck.Name+"/")
+		if deck.SubDeck(subName) != nil {
+			return deck, subName
+		}
+	}
+
+	return nil, ""
+}
+
+// wholeDeck returns a deck for a given name, paths of sub-decks are
+// not accepted by operations that affect a whole deck.
+func (dm DeckManager) wholeDeck(deckName string) (*Deck, error) {
+	deck, subName := dm.findDeck(deckName)
+	if deck == nil || subName != "" {
+		return nil, ErrNotFound
+	}
+
+	return deck, nil
+}
+
+// Cards returns cards of a given deck or sub-deck name.
+func (dm DeckManager) Cards(deckName string) ([]Card, error) {
+	deck, subName := dm.findDeck(deckName)
 	if deck == nil {
 		return nil, ErrNotFound
 	}
 
-	return dm.deckStats(deck)
+	if subName == "" {
+		return deck.Cards, nil
+	}
+
+	result := make([]Card, 0, len(deck.Cards))
+	for _, card := range deck.Cards {
+		if card.SubDeck == subName {
+			result = append(result, card)
+		}
+	}
+
+	return result, nil
+}
+
+// Reviews returns review log for a given deck or sub-deck name.
+// Sub-deck log contains reviews of its current cards.
+func (dm DeckManager) Reviews(deckName string) ([]Review, error) {
+	deck, subName := dm.findDeck(deckName)
+	if deck == nil {
+		return nil, ErrNotFound
+	}
+
+	reviews, err := dm.deckReviews(deck)
+	if err != nil || subName == "" {
+		return reviews, err
+	}
+
+	cards := make(map[string]bool)
+	for _, card := range deck.Cards {
+		if card.SubDeck == subName {
+			cards[card.ID] = true
+		}
+	}
+
+	result := make([]Review, 0, len(reviews))
+	for _, review := range reviews {
+		if cards[review.Card] {
+			result = append(result, review)
+		}
+	}
+
+	return result, nil
+}
+
+// deckReviews returns review log of a deck, reviews logged by older
+// versions using questions are reported for card IDs.
+func (dm DeckManager) deckReviews(deck *Deck) ([]Review, error) {
+	result := make([]Review, 0)
+	err := dm.db.RangeReviews(deck.Name, func(review *Review) bool {
+		if id, ok := deck.legacyKeys[review.Card]; ok {
+			review.Card = id
+		}
+		result = append(result, *review)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// MigrateDeck converts stored card stats of a given deck to another
+// algorithm. Untagged records are treated as saved with from
+// algorithm, if from is empty deck's algorithm will be used.
+// Converted stats and deck's ALGORITHM property are persisted unless
+// dryRun is set.
+func (dm DeckManager) MigrateDeck(deckName string, from, to SRS, dryRun bool) ([]StatsMigration, error) {
+	deck, err := dm.wholeDeck(deckName)
+	if err != nil {
+		return nil, err
+	}
+
+	if from == "" {
+		from = deck.Algorithm
+	}
+
+	if !dryRun {
+		if err := dm.persist(deck); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]StatsMigration, 0)
+	var migrateErr error
+	err = dm.db.RangeRecords(deck.Name, from, dm.clock, deck.Params, func(card string, srs SRS, s *Stats) bool {
+		if s == nil || srs == to || srs == "" && from == to {
+			return true
+		}
+
+		migrated, err := MigrateStats(s, to, dm.clock, deck.Params)
+		if err != nil {
+			migrateErr = err
+			return false
+		}
+
+		result = append(result, StatsMigration{card, s.NextReviewAt(), migrated.NextReviewAt(), migrated})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if migrateErr != nil {
+		return nil, migrateErr
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	for _, m := range result {
+		if err := dm.db.SaveStats(deck.Name, m.Card, m.Stats); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := dm.setAlgorithm(deck, to); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// setAlgorithm writes ALGORITHM property of a deck unless deck
+// already uses provided algorithm.
+func (dm DeckManager) setAlgorithm(deck *Deck, srs SRS) error {
+	if deck.Algorithm == srs {
+		return nil
+	}
+
+	return deck.SetParams(SRSParams{"ALGORITHM": string(srs)})
+}
+
+// ReplayDeck rebuilds card stats of a given deck by replaying its
+// review log through a different algorithm. Cards that have stats
+// but no reviews in the log are migrated instead. Each card uses
+// parameters of its sub-deck, stats and reviews of cards missing
+// from the deck are left intact. Rebuilt stats are persisted along
+// with deck's ALGORITHM property unless dryRun is set, review log is
+// never modified.
+func (dm DeckManager) ReplayDeck(deckName string, to SRS, dryRun bool) ([]StatsMigration, error) {
+	deck, err := dm.wholeDeck(deckName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		if err := dm.persist(deck); err != nil {
+			return nil, err
+		}
+	}
+
+	reviews, err := dm.deckReviews(deck)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make(map[string]Card, len(deck.Cards))
+	for _, card := range deck.Cards {
+		cards[card.ID] = card
+	}
+
+	cardReviews := make(map[string][]Review)
+	for _, review := range reviews {
+		if _, ok := cards[review.Card]; ok {
+			cardReviews[review.Card] = append(cardReviews[review.Card], review)
+		}
+	}
+
+	replayed := make(map[string]*Stats, len(cardReviews))
+	for id, reviews := range cardReviews {
+		stats, err := ReplayReviews(reviews, to, dm.clock, deck.cardParams(cards[id]))
+		if err != nil {
+			return nil, err
+		}
+
+		if stats[id] != nil {
+			replayed[id] = stats[id]
+		}
+	}
+
+	result := make([]StatsMigration, 0, len(replayed))
+	var migrateErr error
+	migrated := make(map[string]bool)
+	err = dm.db.RangeRecords(deck.Name, deck.Algorithm, dm.clock, deck.Params, func(card string, srs SRS, s *Stats) bool {
+		if id, ok := deck.legacyKeys[card]; ok {
+			card = id
+		}
+
+		c, ok := cards[card]
+		if !ok || migrated[card] {
+			return true
+		}
+		migrated[card] = true
+
+		stats := replayed[card]
+		delete(replayed, card)
+		if stats == nil {
+			if s == nil || srs == to || srs == "" && deck.Algorithm == to {
+				return true
+			}
+
+			if stats, migrateErr = MigrateStats(s, to, dm.clock, deck.cardParams(c)); migrateErr != nil {
+				return false
+			}
+		}
+
+		var nextReviewAt time.Time
+		if s != nil {
+			nextReviewAt = s.NextReviewAt()
+		}
+		result = append(result, StatsMigration{card, nextReviewAt, stats.NextReviewAt(), stats})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if migrateErr != nil {
+		return nil, migrateErr
+	}
+
+	for card, stats := range replayed {
+		result = append(result, StatsMigration{card, time.Time{}, stats.NextReviewAt(), stats})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Card < result[j].Card })
+
+	if dryRun {
+		return result, nil
+	}
+
+	for _, m := range result {
+		if err := dm.db.SaveStats(deck.Name, m.Card, m.Stats); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := dm.setAlgorithm(deck, to); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// OptimizeDeck searches algorithm parameters of a given deck that
+// best predict outcomes of its review log. Found parameters are
+// written into deck's property drawer unless dryRun is set.
+func (dm DeckManager) OptimizeDeck(deckName string, dryRun bool) (*OptimizeResult, error) {
+	deck, err := dm.wholeDeck(deckName)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := dm.deckReviews(deck)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := OptimizeParams(reviews, deck.Algorithm, deck.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := deck.SetParams(result.Params); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RepairDeck merges stats records of a given deck that were saved
+// for the same card using different keys, e.g. questions rendered
+// in different output formats by older versions. The most recently
+// reviewed record is kept under card ID, the rest are removed along
+// with review log entries being moved to card ID. Changes are
+// persisted unless dryRun is set.
+func (dm DeckManager) RepairDeck(deckName string, dryRun bool) ([]StatsRepair, error) {
+	deck, err := dm.wholeDeck(deckName)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string, len(deck.Cards)+len(deck.legacyKeys))
+	for key, id := range deck.legacyKeys {
+		ids[key] = id
+	}
+	for _, card := range deck.Cards {
+		ids[card.ID] = card.ID
+	}
+
+	records := make(map[string]map[string]*Stats)
+	err = dm.db.RangeRecords(deck.Name, deck.Algorithm, dm.clock, deck.Params, func(card string, srs SRS, s *Stats) bool {
+		id, ok := ids[card]
+		if !ok || s == nil {
+			return true
+		}
+
+		if records[id] == nil {
+			records[id] = make(map[string]*Stats)
+		}
+		records[id][card] = s
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]StatsRepair, 0)
+	for _, card := range deck.Cards {
+		keys := records[card.ID]
+		if len(keys) == 0 || (len(keys) == 1 && keys[card.ID] != nil) {
+			continue
+		}
+
+		repair := StatsRepair{Card: card.ID, Question: card.Question, Merged: make([]string, 0, len(keys))}
+		var latest time.Time
+		for key, s := range keys {
+			if key != card.ID {
+				repair.Merged = append(repair.Merged, key)
+			}
+
+			if reviewedAt := lastReviewedAt(s); repair.Stats == nil || reviewedAt.After(latest) {
+				repair.Stats, latest = s, reviewedAt
+			}
+		}
+		sort.Strings(repair.Merged)
+		result = append(result, repair)
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	if err := deck.WriteIDs(); err != nil {
+		return nil, err
+	}
+
+	for _, r := range result {
+		if err := dm.db.SaveStats(deck.Name, r.Card, r.Stats); err != nil {
+			return nil, err
+		}
+
+		for _, key := range r.Merged {
+			if err := dm.db.DeleteStats(deck.Name, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := dm.db.RenameCards(deck.Name, deck.legacyKeys); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// OrphanedStats returns stats records that don't belong to any card
+// of available decks, e.g. records of removed cards or renamed
+// decks. Records are matched by card ID to cards that don't have
+// stats, records saved using question text are matched to cards
+// with similar questions. Records of duplicated cards are not
+// reported, use RepairDeck to merge them.
+func (dm DeckManager) OrphanedStats() ([]OrphanedStats, error) {
+	decks := make(map[string]*Deck, len(dm.decks))
+	for _, deck := range dm.decks {
+		decks[deck.Name] = deck
+	}
+
+	names, err := dm.db.Decks()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	stored := make(map[string]map[string]bool)
+	result := make([]OrphanedStats, 0)
+	for _, name := range names {
+		algorithm, params := SRS(SRSSupermemo2PlusCustom), SRSParams(nil)
+		if deck := decks[name]; deck != nil {
+			algorithm, params = deck.Algorithm, deck.Params
+		}
+
+		stored[name] = make(map[string]bool)
+		err := dm.db.RangeRecords(name, algorithm, dm.clock, params, func(card string, srs SRS, s *Stats) bool {
+			stored[name][card] = true
+			deck := decks[name]
+			if deck != nil && deck.legacyKeys[card] != "" {
+				return true
+			}
+
+			if deck != nil {
+				for _, c := range deck.Cards {
+					if c.ID == card {
+						return true
+					}
+				}
+			}
+
+			if srs == "" && deck != nil {
+				srs = deck.Algorithm
+			}
+			result = append(result, OrphanedStats{Deck: name, Card: card, Algorithm: srs, Stats: s})
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	type candidate struct {
+		orphan     int
+		deck       *Deck
+		card       Card
+		similarity float64
+	}
+
+	candidates := make([]candidate, 0)
+	for idx, orphan := range result {
+		for _, deck := range dm.decks {
+			if (decks[orphan.Deck] != nil || orphan.Stats == nil) && deck.Name != orphan.Deck {
+				continue
+			}
+
+			for _, card := range deck.Cards {
+				if stored[deck.Name][card.ID] {
+					continue
+				}
+
+				sim := similarity(orphan.Card, card.RawQuestion)
+				for key, id := range deck.legacyKeys {
+					if id == card.ID {
+						sim = math.Max(sim, similarity(orphan.Card, key))
+					}
+				}
+				if orphan.Card == card.ID {
+					sim = 1
+				}
+
+				if sim >= orphanMatchThreshold {
+					candidates = append(candidates, candidate{idx, deck, card, sim})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+
+	linked := make(map[string]bool)
+	for _, c := range candidates {
+		orphan := &result[c.orphan]
+		if orphan.Match != "" || linked[c.deck.Name+"\x00"+c.card.ID] {
+			continue
+		}
+
+		orphan.MatchDeck, orphan.Match = c.deck.Name, c.card.ID
+		orphan.Question, orphan.Similarity = c.card.Question, c.similarity
+		linked[c.deck.Name+"\x00"+c.card.ID] = true
+	}
+
+	return result, nil
+}
+
+// CollectGarbage re-links provided orphaned records that have a
+// match to matched cards and deletes the rest. Review log entries
+// are moved along with re-linked records of the same deck, review
+// log is never deleted.
+func (dm DeckManager) CollectGarbage(orphans []OrphanedStats) error {
+	for _, orphan := range orphans {
+		if orphan.Match != "" {
+			deck, err := dm.wholeDeck(orphan.MatchDeck)
+			if err != nil {
+				return err
+			}
+			if err := dm.persist(deck); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case orphan.Match == "":
+			if err := dm.db.DeleteStats(orphan.Deck, orphan.Card); err != nil {
+				return err
+			}
+		case orphan.MatchDeck == orphan.Deck:
+			if err := dm.db.RenameCards(orphan.Deck, map[string]string{orphan.Card: orphan.Match}); err != nil {
+				return err
+			}
+		default:
+			stats, err := dm.relinkedStats(orphan)
+			if err != nil {
+				return err
+			}
+			if err := dm.db.SaveStats(orphan.MatchDeck, orphan.Match, stats); err != nil {
+				return err
+			}
+			if err := dm.db.DeleteStats(orphan.Deck, orphan.Card); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Duplicates returns cards that share a question with another card
+// of the same deck. Such cards are indistinguishable during review,
+// questions should be reworded to tell them apart.
+func (dm DeckManager) Duplicates() []DuplicateCards {
+	result := make([]DuplicateCards, 0)
+	for _, deck := range dm.decks {
+		result = append(result, deck.Duplicates...)
+	}
+
+	return result
+}
+
+// relinkedStats returns stats of an orphaned record for a matched
+// deck, untagged records are boxed to the algorithm of matched deck.
+func (dm DeckManager) relinkedStats(orphan OrphanedStats) (*Stats, error) {
+	if orphan.Algorithm != "" {
+		return orphan.Stats, nil
+	}
+
+	deck, err := dm.wholeDeck(orphan.MatchDeck)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats *Stats
+	err = dm.db.RangeRecords(orphan.Deck, deck.Algorithm, dm.clock, deck.Params, func(card string, srs SRS, s *Stats) bool {
+		if card != orphan.Card {
+			return true
+		}
+
+		stats = s
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil {
+		return nil, ErrNotFound
+	}
+
+	return stats, nil
 }
 
-func (dm DeckManager) deckStats(deck *Deck) ([]CardWithStats, error) {
+// InconsistentCards returns cards of a given deck that have stats
+// saved with an algorithm different from the deck's one.
+func (dm DeckManager) InconsistentCards(deckName string) (map[string]SRS, error) {
+	deck, err := dm.wholeDeck(deckName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]SRS)
+	err = dm.db.RangeRecords(deck.Name, deck.Algorithm, dm.clock, deck.Params, func(card string, srs SRS, s *Stats) bool {
+		if srs != "" && srs != deck.Algorithm {
+			result[card] = srs
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// deckStats returns stats of deck cards along with cards that have
+// stats saved with a different algorithm. Such cards are excluded
+// from results, so their stats are not overwritten by reviews.
+func (dm DeckManager) deckStats(deck *Deck) ([]CardWithStats, map[string]SRS, error) {
 	stats := make(map[string]*Stats)
-	err := dm.db.RangeStats(deck.Name, deck.Algorithm, func(card string, s *Stats) bool {
+	err := dm.db.RangeStats(deck.Name, deck.Algorithm, dm.clock, deck.Params, func(card string, s *Stats) bool {
 		stats[card] = s
 		return true
 	})
+	mismatched := make(map[string]SRS)
+	if mErr, ok := err.(*MismatchError); ok {
+		mismatched, err = mErr.Cards, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	mismatchedIDs := make(map[string]SRS, len(mismatched))
+	for key, srs := range mismatched {
+		if id, ok := deck.legacyKeys[key]; ok {
+			key = id
+		}
+		mismatchedIDs[key] = srs
+	}
+
+	// stats saved by older versions using questions are used until
+	// they are moved to card IDs, the latest one is used for cards
+	// with multiple such records
+	legacy := make(map[string]*Stats)
+	for key, id := range deck.legacyKeys {
+		s := stats[key]
+		if s == nil || key == id || stats[id] != nil {
+			continue
+		}
+		if prev := legacy[id]; prev == nil || lastReviewedAt(s).After(lastReviewedAt(prev)) {
+			legacy[id] = s
+		}
+	}
+	for id, s := range legacy {
+		stats[id] = s
 	}
 
 	result := make([]CardWithStats, 0, len(deck.Cards))
+	inconsistent := make(map[string]SRS)
 	for _, card := range deck.Cards {
-		if stats[card.Question] != nil {
-			result = append(result, CardWithStats{card, stats[card.Question]})
-		} else {
-			result = append(result, CardWithStats{card, NewStats(deck.Algorithm)})
+		if s := stats[card.ID]; s != nil {
+			if card.SubDeck != "" {
+				if s, err = reboxStats(s, deck.Algorithm, dm.clock, deck.cardParams(card)); err != nil {
+					return nil, nil, err
+				}
+			}
+			result = append(result, CardWithStats{card, s})
+			continue
+		}
+
+		if srs, ok := mismatchedIDs[card.ID]; ok {
+			inconsistent[card.ID] = srs
+			continue
+		}
+
+		s, err := NewStats(deck.Algorithm, dm.clock, deck.cardParams(card))
+		if err != nil {
+			return nil, nil, err
 		}
+		result = append(result, CardWithStats{card, s})
 	}
 
-	return result, nil
+	return result, inconsistent, nil
 }
 
-func (dm DeckManager) reviewDeck(deck *Deck, total int) (nextReviewAt time.Time, cards []CardWithStats, err error) {
+func (dm DeckManager) reviewDeck(deck *Deck, subName string, filter TagFilter, total int) (nextReviewAt time.Time, cards []CardWithStats, err error) {
 	if fErr := deck.Reload(); fErr != nil {
 		err = fErr
 		return
 	}
 
-	stats, sErr := dm.deckStats(deck)
+	stats, _, sErr := dm.deckStats(deck)
 	if sErr != nil {
 		err = sErr
 		return
 	}
 
-	sort.Slice(stats, func(i, j int) bool {
-		return stats[j].SRSAlgorithm.Less(stats[i].Stats.SRSAlgorithm)
+	filtered := make([]CardWithStats, 0, len(stats))
+	for _, s := range stats {
+		if filter.Match(s.Tags) {
+			filtered = append(filtered, s)
+		}
+	}
+
+	nextReviewAt, cards = dm.readyCards(filtered, subName, total)
+	return
+}
+
+// readyCards returns up to total cards ready for review along with
+// the earliest review time. Cards are limited to a sub-deck if
+// subName is not empty.
+func (dm DeckManager) readyCards(stats []CardWithStats, subName string, total int) (nextReviewAt time.Time, cards []CardWithStats) {
+	filtered := make([]CardWithStats, 0, len(stats))
+	for _, s := range stats {
+		if subName == "" || s.SubDeck == subName {
+			filtered = append(filtered, s)
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[j].SRSAlgorithm.Less(filtered[i].Stats.SRSAlgorithm)
 	})
 
-	if len(stats) > 0 {
-		nextReviewAt = stats[0].NextReviewAt()
+	if len(filtered) > 0 {
+		nextReviewAt = filtered[0].NextReviewAt()
 	}
 
-	cards = make([]CardWithStats, 0, len(stats))
-	for _, s := range stats {
+	cards = make([]CardWithStats, 0, len(filtered))
+	for _, s := range filtered {
 		if total > 0 && len(cards) == total {
 			break
 		}
 
-		if !s.IsReady() {
+		if !s.IsReady(dm.clock) {
 			continue
 		}
 
@@ -156,3 +1097,67 @@ func (dm DeckManager) reviewDeck(deck *Deck, total int) (nextReviewAt time.Time,
 
 	return
 }
+
+// reboxStats returns a copy of stats boxed to an algorithm
+// configured using provided params.
+func reboxStats(s *Stats, srs SRS, clock Clock, params SRSParams) (*Stats, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("json: %s", err)
+	}
+
+	result, err := NewStats(srs, clock, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("json: %s", err)
+	}
+
+	return result, nil
+}
+
+// similarity returns normalised Levenshtein similarity of two
+// strings within [0, 1] range.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(longest)
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
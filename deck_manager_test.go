@@ -1,25 +1,54 @@
 package leaf
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
 )
 
+// copyFixtures copies deck fixtures into a temporary directory, tests
+// operate on copies since deck files are updated with card IDs.
+func copyFixtures(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "decks")
+	require.NoError(t, err)
+
+	for _, name := range []string{"hiragana.org", "org-mode.org"} {
+		data, err := ioutil.ReadFile(filepath.Join("fixtures", name))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+
+	return dir
+}
+
 func TestDeckManager(t *testing.T) {
+	fixtures := copyFixtures(t)
+	defer os.RemoveAll(fixtures)
+
 	tmpfile, err := ioutil.TempFile("", "leaf.db")
 	require.NoError(t, err)
 	defer os.Remove(tmpfile.Name())
 
-	db, err := OpenBoltStore(tmpfile.Name())
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	dm, err := NewDeckManager(DeckSource{Roots: []string{fixtures}}, db, OutputFormatOrg, clock)
 	require.NoError(t, err)
 
-	dm, err := NewDeckManager("./fixtures", db, OutputFormatOrg)
+	cards, err := dm.Cards("Hiragana")
 	require.NoError(t, err)
+	ids := make(map[string]string, len(cards))
+	for _, card := range cards {
+		ids[card.Question] = card.ID
+	}
 
 	t.Run("ReviewDecks", func(t *testing.T) {
 		decks, err := dm.ReviewDecks()
@@ -29,18 +58,18 @@ func TestDeckManager(t *testing.T) {
 		deck := decks[0]
 		assert.Equal(t, "Hiragana", deck.Name)
 		assert.Equal(t, 46, deck.CardsReady)
-		assert.InDelta(t, time.Since(deck.NextReviewAt), 0, float64(time.Minute))
+		assert.Equal(t, clock.Now(), deck.NextReviewAt)
 	})
 
 	t.Run("ReviewSession", func(t *testing.T) {
-		session, err := dm.ReviewSession("Hiragana")
+		session, err := dm.ReviewSession("Hiragana", "")
 		require.NoError(t, err)
 		assert.Equal(t, 20, session.Total())
 
 		question := session.Next()
 		require.NoError(t, session.Again())
-		err = db.RangeStats("Hiragana", SRSSupermemo2PlusCustom, func(card string, s *Stats) bool {
-			if card != question {
+		err = db.RangeStats("Hiragana", SRSSupermemo2PlusCustom, clock, nil, func(card string, s *Stats) bool {
+			if card != ids[question] {
 				return true
 			}
 
@@ -53,6 +82,88 @@ func TestDeckManager(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("Reviews", func(t *testing.T) {
+		reviews, err := dm.Reviews("Hiragana")
+		require.NoError(t, err)
+		require.Len(t, reviews, 1)
+
+		review := reviews[0]
+		assert.Equal(t, "Hiragana", review.Deck)
+		assert.NotEmpty(t, review.Card)
+		assert.Equal(t, ReviewScoreAgain, review.Score)
+	})
+
+	t.Run("MigrateDeck", func(t *testing.T) {
+		s := &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}
+		s.Advance(1)
+		require.NoError(t, db.SaveStats("Hiragana", ids["か"], s))
+
+		migrations, err := dm.MigrateDeck("Hiragana", "", SRSFSRS, true)
+		require.NoError(t, err)
+		require.Len(t, migrations, 1)
+
+		m := migrations[0]
+		assert.Equal(t, ids["か"], m.Card)
+		assert.Equal(t, m.NextReviewAt, m.MigratedReviewAt)
+		_, ok := m.Stats.SRSAlgorithm.(*FSRS)
+		assert.True(t, ok)
+
+		err = db.RangeStats("Hiragana", SRSSupermemo2PlusCustom, clock, nil, func(card string, s *Stats) bool {
+			_, ok := s.SRSAlgorithm.(*Supermemo2PlusCustom)
+			assert.True(t, ok)
+			return true
+		})
+		require.NoError(t, err)
+
+		_, err = dm.MigrateDeck("Missing", "", SRSFSRS, true)
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("InconsistentCards", func(t *testing.T) {
+		cards, err := dm.InconsistentCards("Hiragana")
+		require.NoError(t, err)
+		assert.Empty(t, cards)
+	})
+
+	t.Run("ReplayDeck", func(t *testing.T) {
+		migrations, err := dm.ReplayDeck("Hiragana", SRSFSRS, true)
+		require.NoError(t, err)
+		require.Len(t, migrations, 1)
+
+		m := migrations[0]
+		assert.Equal(t, ids["か"], m.Card)
+		_, ok := m.Stats.SRSAlgorithm.(*FSRS)
+		assert.True(t, ok)
+
+		_, err = dm.ReplayDeck("Missing", SRSFSRS, true)
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("OptimizeDeck", func(t *testing.T) {
+		_, err := dm.OptimizeDeck("Hiragana", true)
+		assert.EqualError(t, err, "optimize: not enough reviews")
+
+		for _, days := range []int{0, 1, 3, 4} {
+			review := &Review{
+				Deck:       "Hiragana",
+				Card:       ids["か"],
+				ReviewedAt: clock.Now().Add(time.Duration(days) * 24 * time.Hour),
+				Score:      ReviewScoreEasy,
+				Rating:     1,
+			}
+			require.NoError(t, db.LogReview(review))
+		}
+
+		result, err := dm.OptimizeDeck("Hiragana", true)
+		require.NoError(t, err)
+		assert.Equal(t, SRSSupermemo2PlusCustom, string(result.Algorithm))
+		assert.Equal(t, 3, result.Reviews)
+		assert.True(t, result.Loss <= result.InitialLoss)
+
+		_, err = dm.OptimizeDeck("Missing", true)
+		assert.Equal(t, ErrNotFound, err)
+	})
+
 	t.Run("DeckStats", func(t *testing.T) {
 		stats, err := dm.DeckStats("Hiragana")
 		require.NoError(t, err)
@@ -64,3 +175,734 @@ func TestDeckManager(t *testing.T) {
 		assert.InDelta(t, 0.3, sm.Difficulty, 0.01)
 	})
 }
+
+func TestDeckManagerCardIDs(t *testing.T) {
+	fixtures := copyFixtures(t)
+	defer os.RemoveAll(fixtures)
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}
+	s.Advance(1)
+	require.NoError(t, db.SaveStats("Hiragana", "か", s))
+	require.NoError(t, db.LogReview(&Review{Deck: "Hiragana", Card: "か", Score: ReviewScoreEasy, Rating: 1}))
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{fixtures}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	stats, err := dm.DeckStats("Hiragana")
+	require.NoError(t, err)
+
+	var card *CardWithStats
+	for idx := range stats {
+		if stats[idx].Question == "か" {
+			card = &stats[idx]
+		}
+	}
+	require.NotNil(t, card)
+	assert.NotEmpty(t, card.ID)
+	assert.True(t, card.NextReviewAt().After(clock.Now()))
+
+	reviews, err := dm.Reviews("Hiragana")
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	assert.Equal(t, card.ID, reviews[0].Card)
+}
+
+func TestDeckManagerSubDecks(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "decks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	content := "* Kana\n:PROPERTIES:\n:SUBDECKS: t\n:PER_REVIEW: 3\n:END:\n" +
+		"** A-row\n*** あ\n:PROPERTIES:\n:ID: a\n:END:\na\n*** い\n:PROPERTIES:\n:ID: i\n:END:\ni\n" +
+		"** K-row\n:PROPERTIES:\n:RATER: self\n:PER_REVIEW: 1\n:END:\n*** か\n:PROPERTIES:\n:ID: ka\n:END:\nka\n*** き\n:PROPERTIES:\n:ID: ki\n:END:\nki\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "kana.org"), []byte(content), 0644))
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}
+	s.Advance(1)
+	require.NoError(t, db.SaveStats("Kana", "ka", s))
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{dir}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	decks, err := dm.ReviewDecks()
+	require.NoError(t, err)
+	require.Len(t, decks, 3)
+	assert.Equal(t, DeckStats{"Kana", 3, clock.Now(), 0}, decks[0])
+	assert.Equal(t, DeckStats{"Kana/A-row", 2, clock.Now(), 0}, decks[1])
+	assert.Equal(t, DeckStats{"Kana/K-row", 1, clock.Now(), 0}, decks[2])
+
+	stats, err := dm.DeckStats("Kana/K-row")
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, "ka", stats[0].ID)
+	assert.True(t, stats[0].NextReviewAt().After(clock.Now()))
+
+	_, err = dm.DeckStats("Kana/N-row")
+	assert.Equal(t, ErrNotFound, err)
+
+	session, err := dm.ReviewSession("Kana/K-row", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, session.Total())
+	assert.Equal(t, RatingTypeSelf, session.RatingType())
+	assert.Equal(t, "き", session.Next())
+	require.NoError(t, session.Again())
+
+	reviews, err := dm.Reviews("Kana")
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	assert.Equal(t, "Kana", reviews[0].Deck)
+	assert.Equal(t, "ki", reviews[0].Card)
+
+	reviews, err = dm.Reviews("Kana/A-row")
+	require.NoError(t, err)
+	assert.Empty(t, reviews)
+
+	cards, err := dm.Cards("Kana/K-row")
+	require.NoError(t, err)
+	require.Len(t, cards, 2)
+	assert.Equal(t, "ka", cards[0].ID)
+	assert.Equal(t, "ki", cards[1].ID)
+
+	_, err = dm.Cards("Kana/N-row")
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = dm.MigrateDeck("Kana/K-row", "", SRSFSRS, true)
+	assert.Equal(t, ErrNotFound, err)
+
+	session, err = dm.ReviewSession("Kana", "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, session.Total())
+	assert.Equal(t, RatingTypeAuto, session.RatingType())
+}
+
+func TestDeckManagerTags(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "decks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	content := "* Japanese :japanese:\n" +
+		"** 行く :verbs:irregular:\n:PROPERTIES:\n:ID: iku\n:END:\nto go\n" +
+		"** 食べる :verbs:\n:PROPERTIES:\n:ID: taberu\n:END:\nto eat\n" +
+		"** 猫 :nouns:\n:PROPERTIES:\n:ID: neko\n:END:\ncat\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "japanese.org"), []byte(content), 0644))
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	dm, err := NewDeckManager(DeckSource{Roots: []string{dir}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	tcs := []struct {
+		filter string
+		total  int
+	}{
+		{"", 3},
+		{"+japanese", 3},
+		{"+verbs", 2},
+		{"+verbs -irregular", 1},
+		{"-verbs", 1},
+		{"+kanji", 0},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.filter, func(t *testing.T) {
+			session, err := dm.ReviewSession("Japanese", tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tc.total, session.Total())
+		})
+	}
+
+	session, err := dm.ReviewSession("Japanese", "+verbs -irregular")
+	require.NoError(t, err)
+	assert.Equal(t, "食べる", session.Next())
+
+	_, err = dm.ReviewSession("Japanese", "verbs|nouns")
+	assert.Error(t, err)
+}
+
+func TestDeckManagerRepairDeck(t *testing.T) {
+	fixtures := copyFixtures(t)
+	defer os.RemoveAll(fixtures)
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	old := &Stats{NewEbisu(clock, DefaultEbisuParams())}
+	old.Advance(1)
+	require.NoError(t, db.SaveStats("Org-mode", "<em>emphasis</em>", old))
+
+	clock.Advance(24 * time.Hour)
+	recent := &Stats{NewEbisu(clock, DefaultEbisuParams())}
+	recent.Advance(1)
+	require.NoError(t, db.SaveStats("Org-mode", "/emphasis/", recent))
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{fixtures}}, db, OutputFormatHTML, clock)
+	require.NoError(t, err)
+
+	repairs, err := dm.RepairDeck("Org-mode", true)
+	require.NoError(t, err)
+	require.Len(t, repairs, 1)
+
+	r := repairs[0]
+	assert.Equal(t, "<em>emphasis</em>", r.Question)
+	// legacy records are not moved to card IDs on start
+	assert.Equal(t, []string{"/emphasis/", "<em>emphasis</em>"}, r.Merged)
+	assert.Equal(t, recent.NextReviewAt(), r.Stats.NextReviewAt())
+
+	repairs, err = dm.RepairDeck("Org-mode", false)
+	require.NoError(t, err)
+	require.Len(t, repairs, 1)
+
+	cards := 0
+	err = db.RangeRecords("Org-mode", SRSEbisu, clock, nil, func(card string, srs SRS, s *Stats) bool {
+		cards++
+		assert.Equal(t, r.Card, card)
+		assert.Equal(t, recent.NextReviewAt(), s.NextReviewAt())
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, cards)
+
+	repairs, err = dm.RepairDeck("Org-mode", false)
+	require.NoError(t, err)
+	assert.Empty(t, repairs)
+
+	_, err = dm.RepairDeck("Missing", true)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestDeckManagerOrphanedStats(t *testing.T) {
+	fixtures := copyFixtures(t)
+	defer os.RemoveAll(fixtures)
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	decks, err := OpenDeck(filepath.Join(fixtures, "org-mode.org"), OutputFormatOrg)
+	require.NoError(t, err)
+	require.NoError(t, decks[0].WriteIDs())
+	require.Len(t, decks, 1)
+	deck := decks[0]
+	emphasis, underlined := deck.Cards[0], deck.Cards[1]
+
+	s := &Stats{NewEbisu(clock, DefaultEbisuParams())}
+	require.NoError(t, db.SaveStats("Org-mode", "_underlinde_", s))
+	require.NoError(t, db.SaveStats("Org-mode", "removed card", s))
+	require.NoError(t, db.SaveStats("Old deck", emphasis.ID, s))
+	require.NoError(t, db.SaveStats("Hiragana", "か", s))
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{fixtures}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	orphans, err := dm.OrphanedStats()
+	require.NoError(t, err)
+	require.Len(t, orphans, 3)
+
+	assert.Equal(t, "Old deck", orphans[0].Deck)
+	assert.Equal(t, "Org-mode", orphans[0].MatchDeck)
+	assert.Equal(t, emphasis.ID, orphans[0].Match)
+	assert.Equal(t, 1.0, orphans[0].Similarity)
+
+	assert.Equal(t, "_underlinde_", orphans[1].Card)
+	assert.Equal(t, "Org-mode", orphans[1].MatchDeck)
+	assert.Equal(t, underlined.ID, orphans[1].Match)
+	assert.Equal(t, "_underlined_", orphans[1].Question)
+	assert.InDelta(t, 0.83, orphans[1].Similarity, 0.01)
+
+	assert.Equal(t, "removed card", orphans[2].Card)
+	assert.Empty(t, orphans[2].Match)
+
+	require.NoError(t, dm.CollectGarbage(orphans))
+
+	orphans, err = dm.OrphanedStats()
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+
+	cards := []string{}
+	err = db.RangeRecords("Org-mode", SRSEbisu, clock, nil, func(card string, srs SRS, s *Stats) bool {
+		cards = append(cards, card)
+		return true
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{emphasis.ID, underlined.ID}, cards)
+}
+
+func TestDeckManagerOrphanedStatsUntagged(t *testing.T) {
+	fixtures := copyFixtures(t)
+	defer os.RemoveAll(fixtures)
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	decks, err := OpenDeck(filepath.Join(fixtures, "org-mode.org"), OutputFormatOrg)
+	require.NoError(t, err)
+	require.NoError(t, decks[0].WriteIDs())
+	emphasis := decks[0].Cards[0]
+
+	s := &Stats{NewEbisu(clock, DefaultEbisuParams())}
+	s.Advance(1)
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	err = db.(*boltStore).bolt.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("Old deck"))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(emphasis.ID), data)
+	})
+	require.NoError(t, err)
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{fixtures}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	orphans, err := dm.OrphanedStats()
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, emphasis.ID, orphans[0].Match)
+	assert.Empty(t, orphans[0].Algorithm)
+
+	// listing keeps records untagged
+	err = db.(*boltStore).bolt.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, data, tx.Bucket([]byte("Old deck")).Get([]byte(emphasis.ID)))
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, dm.CollectGarbage(orphans))
+
+	stats, err := dm.DeckStats("Org-mode")
+	require.NoError(t, err)
+	assert.Equal(t, SRS(SRSEbisu), stats[0].Algorithm())
+	assert.Equal(t, s.NextReviewAt(), stats[0].NextReviewAt())
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, similarity("", ""))
+	assert.Equal(t, 1.0, similarity("foo", "foo"))
+	assert.Equal(t, 0.0, similarity("foo", "bar"))
+	assert.InDelta(t, 0.67, similarity("foo", "fob"), 0.01)
+	assert.InDelta(t, 0.5, similarity("かな", "かさ"), 0.01)
+}
+
+func TestDeckManagerDeckSource(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	root1, err := ioutil.TempDir("", "decks")
+	require.NoError(t, err)
+	defer os.RemoveAll(root1)
+
+	root2, err := ioutil.TempDir("", "decks")
+	require.NoError(t, err)
+	defer os.RemoveAll(root2)
+
+	files := map[string]string{
+		filepath.Join(root1, "top.org"):                        "* Top\n** foo\nbar\n",
+		filepath.Join(root1, "languages", "japanese", "a.org"): "* Hiragana\n** foo\nbar\n",
+		filepath.Join(root1, "work", "k8s", "a.org"):           "* Hiragana\n** foo\nbar\n",
+		filepath.Join(root1, "work", "k8s", "draft.org"):       "* Draft\n** foo\nbar\n",
+		filepath.Join(root1, ".git", "a.org"):                  "* Hidden\n** foo\nbar\n",
+		filepath.Join(root1, "languages", "japanese", "a.txt"): "* Text\n** foo\nbar\n",
+		filepath.Join(root2, "other.org"):                      "* Other\n** foo\nbar\n",
+		filepath.Join(root2, "notes.md"):                       "# Notes\n## foo\nbar\n",
+		filepath.Join(root2, "languages", "japanese", "b.org"): "* Katakana\n** foo\nbar\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		require.NoError(t, ioutil.WriteFile(name, []byte(content), 0644))
+	}
+
+	deckNames := func(source DeckSource) []string {
+		dm, err := NewDeckManager(source, db, OutputFormatOrg, NewSimulatedClock(time.Unix(100, 0)))
+		require.NoError(t, err)
+
+		decks, err := dm.ReviewDecks()
+		require.NoError(t, err)
+
+		names := []string{}
+		for _, deck := range decks {
+			names = append(names, deck.Name)
+		}
+		return names
+	}
+
+	assert.Equal(
+		t,
+		[]string{"languages/japanese/Hiragana", "Top", "work/k8s/Hiragana", "work/k8s/Draft", "languages/japanese/Katakana", "Notes", "Other"},
+		deckNames(DeckSource{Roots: []string{root1, root2}}),
+	)
+
+	assert.Equal(
+		t,
+		[]string{"languages/japanese/Hiragana", "languages/japanese/Katakana"},
+		deckNames(DeckSource{Roots: []string{root1, root2}, Include: []string{"languages/**"}}),
+	)
+
+	assert.Equal(
+		t,
+		[]string{"languages/japanese/Hiragana", "Top", "work/k8s/Hiragana"},
+		deckNames(DeckSource{Roots: []string{root1}, Exclude: []string{"draft.org"}}),
+	)
+
+	assert.Equal(
+		t,
+		[]string{"Top"},
+		deckNames(DeckSource{Roots: []string{root1}, Include: []string{"*.org"}, Exclude: []string{"**/a.org", "work/*/*"}}),
+	)
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{root1}}, db, OutputFormatOrg, SystemClock)
+	require.NoError(t, err)
+	_, err = dm.DeckStats("work/k8s/Hiragana")
+	require.NoError(t, err)
+
+	_, err = NewDeckManager(DeckSource{Roots: []string{root1, root1}}, db, OutputFormatOrg, SystemClock)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "deck languages/japanese/Hiragana is defined in")
+
+	_, err = NewDeckManager(DeckSource{Roots: []string{root1}, Include: []string{"["}}, db, OutputFormatOrg, SystemClock)
+	assert.EqualError(t, err, `deck: invalid pattern "["`)
+}
+
+func TestDeckManagerDuplicates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	deck := "* Words\n** bank\n:PROPERTIES:\n:ID: bank-1\n:END:\nriver side\n** bank\n:PROPERTIES:\n:ID: bank-2\n:END:\nmoney\n** cat\n:PROPERTIES:\n:ID: cat\n:END:\nneko\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "words.org"), []byte(deck), 0644))
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+	defer db.Close()
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}
+	s.Advance(1)
+	require.NoError(t, db.SaveStats("Words", "bank", s))
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{dir}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	duplicates := dm.Duplicates()
+	require.Len(t, duplicates, 1)
+	assert.Equal(t, DuplicateCards{"Words", "bank", []string{"bank-1", "bank-2"}}, duplicates[0])
+	assert.Equal(t, `deck Words: cards bank-1, bank-2 share question "bank"`, duplicates[0].String())
+
+	// stats saved by question are kept by the first card
+	stats, err := dm.DeckStats("Words")
+	require.NoError(t, err)
+	assert.True(t, stats[0].NextReviewAt().After(clock.Now()))
+	assert.False(t, stats[1].NextReviewAt().After(clock.Now()))
+
+	session, err := dm.ReviewSession("Words", "")
+	require.NoError(t, err)
+	answers := make(map[string]string)
+	for session.Left() > 0 {
+		answers[session.CardID()] = session.CorrectAnswer()
+		require.NoError(t, session.Rate(1, ReviewScoreEasy))
+	}
+	assert.Equal(t, map[string]string{"bank-2": "money", "cat": "neko"}, answers)
+}
+
+func TestDeckManagerMigrateDeck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	deck := "* Words\n:PROPERTIES:\n:RATER: self\n:END:\n** cat\n:PROPERTIES:\n:ID: cat\n:END:\nneko\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "words.org"), []byte(deck), 0644))
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+	defer db.Close()
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}
+	s.Advance(1)
+	require.NoError(t, db.SaveStats("Words", "cat", s))
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{dir}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	migrations, err := dm.MigrateDeck("Words", "", SRSFSRS, false)
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "words.org"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), ":ALGORITHM: fsrs\n")
+
+	stats, err := dm.DeckStats("Words")
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, SRS(SRSFSRS), stats[0].Algorithm())
+
+	_, err = dm.ReviewSession("Words", "")
+	assert.NoError(t, err)
+
+	_, err = dm.ReplayDeck("Words", SRSEbisu, false)
+	require.NoError(t, err)
+
+	content, err = ioutil.ReadFile(filepath.Join(dir, "words.org"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), ":ALGORITHM: ebisu\n")
+
+	stats, err = dm.DeckStats("Words")
+	require.NoError(t, err)
+	assert.Equal(t, SRS(SRSEbisu), stats[0].Algorithm())
+}
+
+func TestDeckManagerInconsistentStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	deck := "* Words\n:PROPERTIES:\n:RATER: self\n:END:\n" +
+		"** cat\n:PROPERTIES:\n:ID: cat\n:END:\nneko\n" +
+		"** dog\n:PROPERTIES:\n:ID: dog\n:END:\ninu\n" +
+		"** fox\n:PROPERTIES:\n:ID: fox\n:END:\nkitsune\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "words.org"), []byte(deck), 0644))
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+	defer db.Close()
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, db.SaveStats("Words", "cat", &Stats{NewFSRS(clock, DefaultFSRSParams())}))
+	err = db.(*boltStore).bolt.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("Words"))
+		if err := b.Put([]byte("dog"), []byte(`{"v":1,"srs":"custom","stats":{}}`)); err != nil {
+			return err
+		}
+		return b.Put([]byte("removed"), []byte(`{"v":1,"srs":"custom","stats":{}}`))
+	})
+	require.NoError(t, err)
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{dir}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	decks, err := dm.ReviewDecks()
+	require.NoError(t, err)
+	assert.Equal(t, []DeckStats{{"Words", 1, clock.Now(), 2}}, decks)
+
+	session, err := dm.ReviewSession("Words", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, session.Total())
+	assert.Equal(t, "fox", session.Next())
+
+	inconsistent, err := dm.InconsistentCards("Words")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]SRS{"cat": SRSFSRS, "dog": "custom", "removed": "custom"}, inconsistent)
+
+	_, err = dm.MigrateDeck("Words", "", SRSFSRS, true)
+	require.NoError(t, err)
+	_, err = dm.RepairDeck("Words", true)
+	require.NoError(t, err)
+
+	orphans, err := dm.OrphanedStats()
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, "removed", orphans[0].Card)
+	assert.Equal(t, SRS("custom"), orphans[0].Algorithm)
+	assert.Empty(t, orphans[0].Match)
+
+	require.NoError(t, dm.CollectGarbage(orphans))
+	inconsistent, err = dm.InconsistentCards("Words")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]SRS{"cat": SRSFSRS, "dog": "custom"}, inconsistent)
+}
+
+func TestDeckManagerReplayDeck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	deck := "* Kana\n:PROPERTIES:\n:SUBDECKS: t\n:ALGORITHM: sm2+c\n:END:\n" +
+		"** A-row\n*** あ\n:PROPERTIES:\n:ID: a\n:END:\na\n" +
+		"** K-row\n:PROPERTIES:\n:DESIRED_RETENTION: 0.8\n:END:\n*** か\n:PROPERTIES:\n:ID: ka\n:END:\nka\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "kana.org"), []byte(deck), 0644))
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+	defer db.Close()
+
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}
+	s.Advance(1)
+	for _, card := range []string{"a", "ka", "removed"} {
+		require.NoError(t, db.SaveStats("Kana", card, s))
+		for day := 0; day < 3; day++ {
+			reviewedAt := clock.Now().Add(time.Duration(day) * 72 * time.Hour)
+			require.NoError(t, db.LogReview(&Review{Deck: "Kana", Card: card, ReviewedAt: reviewedAt, Score: ReviewScoreEasy, Rating: 1}))
+		}
+	}
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{dir}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	migrations, err := dm.ReplayDeck("Kana", SRSFSRS, true)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, "a", migrations[0].Card)
+	assert.Equal(t, "ka", migrations[1].Card)
+
+	// lower retention of K-row results in a longer interval
+	assert.True(t, migrations[1].MigratedReviewAt.After(migrations[0].MigratedReviewAt))
+
+	_, err = dm.ReplayDeck("Kana", SRSFSRS, false)
+	require.NoError(t, err)
+
+	stored := map[string]SRS{}
+	err = db.RangeRecords("Kana", SRSSupermemo2PlusCustom, clock, nil, func(card string, srs SRS, s *Stats) bool {
+		stored[card] = srs
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]SRS{"a": SRSFSRS, "ka": SRSFSRS, "removed": SRSSupermemo2PlusCustom}, stored)
+}
+
+func TestDeckManagerWriteIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "words.org")
+	content := "* Words\n** cat\nneko\n** dog\ninu\n"
+	require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// stats saved by older versions using a question
+	clock := NewSimulatedClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}
+	s.Advance(1)
+	require.NoError(t, db.SaveStats("Words", "cat", s))
+	require.NoError(t, db.LogReview(&Review{Deck: "Words", Card: "cat", Score: ReviewScoreEasy, Rating: 1}))
+
+	dm, err := NewDeckManager(DeckSource{Roots: []string{dir}}, db, OutputFormatOrg, clock)
+	require.NoError(t, err)
+
+	t.Run("read-only", func(t *testing.T) {
+		_, err := dm.ReviewDecks()
+		require.NoError(t, err)
+
+		stats, err := dm.DeckStats("Words")
+		require.NoError(t, err)
+		require.Len(t, stats, 2)
+		assert.Equal(t, s.NextReviewAt(), stats[0].NextReviewAt())
+
+		reviews, err := dm.Reviews("Words")
+		require.NoError(t, err)
+		require.Len(t, reviews, 1)
+		assert.Equal(t, stats[0].ID, reviews[0].Card)
+
+		_, err = dm.MigrateDeck("Words", "", SRSFSRS, true)
+		require.NoError(t, err)
+		_, err = dm.ReplayDeck("Words", SRSFSRS, true)
+		require.NoError(t, err)
+		_, err = dm.RepairDeck("Words", true)
+		require.NoError(t, err)
+		_, err = dm.OrphanedStats()
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+
+		cards := []string{}
+		err = db.RangeRecords("Words", SRSSupermemo2PlusCustom, clock, nil, func(card string, srs SRS, s *Stats) bool {
+			cards = append(cards, card)
+			return true
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"cat"}, cards)
+	})
+
+	t.Run("review", func(t *testing.T) {
+		cards, err := dm.Cards("Words")
+		require.NoError(t, err)
+
+		_, err = dm.ReviewSession("Words", "")
+		require.NoError(t, err)
+
+		data, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, "* Words\n** cat\n:PROPERTIES:\n:ID: "+cards[0].ID+"\n:END:\nneko\n** dog\n:PROPERTIES:\n:ID: "+cards[1].ID+"\n:END:\ninu\n", string(data))
+
+		stored := []string{}
+		err = db.RangeRecords("Words", SRSSupermemo2PlusCustom, clock, nil, func(card string, srs SRS, s *Stats) bool {
+			stored = append(stored, card)
+			return true
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{cards[0].ID}, stored)
+
+		reopened, err := NewDeckManager(DeckSource{Roots: []string{dir}}, db, OutputFormatOrg, clock)
+		require.NoError(t, err)
+		reopenedCards, err := reopened.Cards("Words")
+		require.NoError(t, err)
+		assert.Equal(t, cards, reopenedCards)
+	})
+}
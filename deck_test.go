@@ -3,6 +3,8 @@ package leaf
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,8 +14,10 @@ import (
 
 func TestDeck(t *testing.T) {
 	t.Run("OpenDeck", func(t *testing.T) {
-		deck, err := OpenDeck("./fixtures/hiragana.org", OutputFormatOrg)
+		decks, err := OpenDeck("./fixtures/hiragana.org", OutputFormatOrg)
 		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
 		assert.Equal(t, "Hiragana", deck.Name)
 		assert.Equal(t, RatingTypeAuto, deck.RatingType)
 		assert.Equal(t, SRSSupermemo2PlusCustom, string(deck.Algorithm))
@@ -26,8 +30,10 @@ func TestDeck(t *testing.T) {
 	})
 
 	t.Run("OpenRichDeck", func(t *testing.T) {
-		deck, err := OpenDeck("./fixtures/org-mode.org", OutputFormatHTML)
+		decks, err := OpenDeck("./fixtures/org-mode.org", OutputFormatHTML)
 		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
 		assert.Equal(t, "Org-mode", deck.Name)
 		assert.Equal(t, RatingTypeSelf, deck.RatingType)
 		assert.Equal(t, SRSEbisu, string(deck.Algorithm))
@@ -52,23 +58,432 @@ func TestDeck(t *testing.T) {
 		require.NoError(t, err)
 		defer os.Remove(deckfile.Name())
 
-		_, err = deckfile.Write([]byte("* Test\n** foo\nbar\n"))
+		_, err = deckfile.Write([]byte("* Test\n** foo\n:PROPERTIES:\n:ID: 1\n:END:\nbar\n"))
 		require.NoError(t, err)
 		require.NoError(t, deckfile.Sync())
 
-		deck, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		decks, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
 		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
 		require.Len(t, deck.Cards, 1)
 
 		require.NoError(t, deck.Reload())
 		require.Len(t, deck.Cards, 1)
 
 		time.Sleep(100 * time.Millisecond)
-		_, err = deckfile.Write([]byte("** bar\nbaz\n"))
+		_, err = deckfile.Write([]byte("** bar\n:PROPERTIES:\n:ID: 2\n:END:\nbaz\n"))
 		require.NoError(t, err)
 		require.NoError(t, deckfile.Sync())
 
 		require.NoError(t, deck.Reload())
 		require.Len(t, deck.Cards, 2)
 	})
+	t.Run("UnknownAlgorithm", func(t *testing.T) {
+		deckfile, err := ioutil.TempFile("", "deck.org")
+		require.NoError(t, err)
+		defer os.Remove(deckfile.Name())
+
+		_, err = deckfile.Write([]byte("* Test\n:PROPERTIES:\n:ALGORITHM: foo\n:END:\n** foo\nbar\n"))
+		require.NoError(t, err)
+		require.NoError(t, deckfile.Sync())
+
+		_, err = OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown algorithm "foo"`)
+	})
+	t.Run("Params", func(t *testing.T) {
+		deckfile, err := ioutil.TempFile("", "deck.org")
+		require.NoError(t, err)
+		defer os.Remove(deckfile.Name())
+
+		_, err = deckfile.Write([]byte("* Test\n:PROPERTIES:\n:ALGORITHM: sm2\n:MAX_INTERVAL: 30\n:INITIAL_EASE: 2.2\n:END:\n** foo\nbar\n"))
+		require.NoError(t, err)
+		require.NoError(t, deckfile.Sync())
+
+		decks, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
+		assert.Equal(t, "30", deck.Params["MAX_INTERVAL"])
+		assert.Equal(t, "2.2", deck.Params["INITIAL_EASE"])
+
+		time.Sleep(100 * time.Millisecond)
+		require.NoError(t, ioutil.WriteFile(deckfile.Name(), []byte("* Test\n:PROPERTIES:\n:ALGORITHM: sm2\n:MAX_INTERVAL: 0\n:END:\n"), 0644))
+		err = deck.Reload()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "MAX_INTERVAL")
+	})
+
+	t.Run("IDs", func(t *testing.T) {
+		deckfile, err := ioutil.TempFile("", "deck.org")
+		require.NoError(t, err)
+		defer os.Remove(deckfile.Name())
+
+		content := "* Test\n:PROPERTIES:\n:RATER: self\n:END:\n** foo\n#+BEGIN_SRC org\n* not a card\n#+END_SRC\n** bar\n:PROPERTIES:\n:ID: 1\n:END:\nbaz\n** qux\n:PROPERTIES:\n:FOO: bar\n:END:\nquux\n"
+		_, err = deckfile.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, deckfile.Sync())
+
+		decks, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
+		require.Len(t, deck.Cards, 3)
+		assert.Len(t, deck.Cards[0].ID, 36)
+		assert.Equal(t, "1", deck.Cards[1].ID)
+		assert.Len(t, deck.Cards[2].ID, 36)
+		assert.NotEqual(t, deck.Cards[0].ID, deck.Cards[2].ID)
+		assert.Equal(t, "quux", deck.Cards[2].Answer())
+
+		// generated IDs are written only on request
+		data, err := ioutil.ReadFile(deckfile.Name())
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+
+		require.NoError(t, deck.WriteIDs())
+		data, err = ioutil.ReadFile(deckfile.Name())
+		require.NoError(t, err)
+		assert.Equal(
+			t,
+			"* Test\n:PROPERTIES:\n:RATER: self\n:END:\n** foo\n:PROPERTIES:\n:ID: "+deck.Cards[0].ID+
+				"\n:END:\n#+BEGIN_SRC org\n* not a card\n#+END_SRC\n** bar\n:PROPERTIES:\n:ID: 1\n:END:\nbaz\n** qux\n:PROPERTIES:\n:FOO: bar\n:ID: "+
+				deck.Cards[2].ID+"\n:END:\nquux\n",
+			string(data),
+		)
+
+		reopeneds, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, reopeneds, 1)
+		reopened := reopeneds[0]
+		assert.Equal(t, deck.Cards, reopened.Cards)
+	})
+
+	t.Run("CRLF", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "leaf")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		for name, content := range map[string]string{
+			"deck.org": "* Test\r\n:PROPERTIES:\r\n:RATER: self\r\n:END:\r\n** foo\r\nbar\r\n** baz\r\n:PROPERTIES:\r\n:ID: 1\r\n:END:\r\nqux\r\n",
+			"deck.md":  "# Test\r\n<!-- RATER: self -->\r\n## foo\r\nbar\r\n## baz\r\n<!-- ID: 1 -->\r\nqux\r\n",
+		} {
+			filename := filepath.Join(dir, name)
+			require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+			decks, err := OpenDeck(filename, OutputFormatOrg)
+			require.NoError(t, err, name)
+			deck := decks[0]
+			require.NoError(t, deck.WriteIDs(), name)
+			require.NoError(t, deck.SetParams(SRSParams{"ALGORITHM": "sm2"}), name)
+
+			data, err := ioutil.ReadFile(filename)
+			require.NoError(t, err)
+			assert.Equal(t, strings.Count(string(data), "\n"), strings.Count(string(data), "\r\n"), name)
+			assert.Contains(t, string(data), deck.Cards[0].ID, name)
+			assert.True(t, strings.HasSuffix(string(data), "qux\r\n"), name)
+
+			assert.Equal(t, SRSSupermemo2, deck.Algorithm, name)
+			require.Len(t, deck.Cards, 2, name)
+			assert.Equal(t, "foo", deck.Cards[0].Question, name)
+			assert.Equal(t, "1", deck.Cards[1].ID, name)
+			assert.Equal(t, []string{"qux"}, deck.Cards[1].Sides, name)
+		}
+	})
+
+	t.Run("IDsPlacement", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "leaf")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		filename := filepath.Join(dir, "deck.org")
+		content := "* Test\n** foo\nSCHEDULED: <2019-01-01 Tue>\nbar\n** baz\n#+BEGIN_SRC\nqux\n** quux\ncorge\n"
+		require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0600))
+
+		decks, err := OpenDeck(filename, OutputFormatOrg)
+		require.NoError(t, err)
+		deck := decks[0]
+		require.Len(t, deck.Cards, 3)
+		assert.Equal(t, []string{"bar"}, deck.Cards[0].Sides)
+		assert.Equal(t, "quux", deck.Cards[2].Question)
+		require.NoError(t, deck.WriteIDs())
+
+		data, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(
+			t,
+			"* Test\n** foo\nSCHEDULED: <2019-01-01 Tue>\n:PROPERTIES:\n:ID: "+deck.Cards[0].ID+"\n:END:\nbar\n"+
+				"** baz\n:PROPERTIES:\n:ID: "+deck.Cards[1].ID+"\n:END:\n#+BEGIN_SRC\nqux\n"+
+				"** quux\n:PROPERTIES:\n:ID: "+deck.Cards[2].ID+"\n:END:\ncorge\n",
+			string(data),
+		)
+
+		stat, err := os.Stat(filename)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), stat.Mode())
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, files, 1)
+
+		reopened, err := OpenDeck(filename, OutputFormatOrg)
+		require.NoError(t, err)
+		assert.Equal(t, deck.Cards, reopened[0].Cards)
+	})
+
+	t.Run("MultipleDecks", func(t *testing.T) {
+		deckfile, err := ioutil.TempFile("", "deck.org")
+		require.NoError(t, err)
+		defer os.Remove(deckfile.Name())
+
+		content := "* First\n:PROPERTIES:\n:RATER: self\n:END:\n** foo\nbar\n* Second\n:PROPERTIES:\n:ALGORITHM: sm2\n:END:\n** baz\nqux\n** quux\nquuz\n"
+		_, err = deckfile.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, deckfile.Sync())
+
+		decks, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks, 2)
+
+		first, second := decks[0], decks[1]
+		assert.Equal(t, "First", first.Name)
+		assert.Equal(t, RatingTypeSelf, first.RatingType)
+		assert.Equal(t, SRSSupermemo2PlusCustom, string(first.Algorithm))
+		require.Len(t, first.Cards, 1)
+		assert.Equal(t, "foo", first.Cards[0].Question)
+		assert.NotEmpty(t, first.Cards[0].ID)
+
+		assert.Equal(t, "Second", second.Name)
+		assert.Equal(t, RatingTypeAuto, second.RatingType)
+		assert.Equal(t, SRSSupermemo2, second.Algorithm)
+		require.Len(t, second.Cards, 2)
+		assert.Equal(t, "baz", second.Cards[0].Question)
+		assert.NotEmpty(t, second.Cards[1].ID)
+
+		require.NoError(t, second.SetParams(SRSParams{"PER_REVIEW": "5"}))
+		assert.Equal(t, 5, second.PerReview)
+		assert.Equal(t, 20, first.PerReview)
+		require.Len(t, second.Cards, 2)
+
+		data, err := ioutil.ReadFile(deckfile.Name())
+		require.NoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+		content = "* Zeroth\n** new\ncard\n" + string(data)
+		require.NoError(t, ioutil.WriteFile(deckfile.Name(), []byte(content), 0644))
+
+		require.NoError(t, second.Reload())
+		assert.Equal(t, "Second", second.Name)
+		assert.Equal(t, 5, second.PerReview)
+		require.Len(t, second.Cards, 2)
+
+		require.NoError(t, first.Reload())
+		assert.Equal(t, "First", first.Name)
+		require.Len(t, first.Cards, 1)
+	})
+
+	t.Run("SubDecks", func(t *testing.T) {
+		deckfile, err := ioutil.TempFile("", "deck.org")
+		require.NoError(t, err)
+		defer os.Remove(deckfile.Name())
+
+		content := "* Test\n:PROPERTIES:\n:SUBDECKS: t\n:RATER: self\n:PER_REVIEW: 10\n:END:\n" +
+			"** A-row\n*** foo\n:PROPERTIES:\n:ID: 1\n:END:\nbar\n" +
+			"** K-row\n:PROPERTIES:\n:PER_REVIEW: 5\n:MIN_INTERVAL: 0.3\n:END:\n*** baz\n:PROPERTIES:\n:ID: 2\n:END:\nqux\n"
+		_, err = deckfile.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, deckfile.Sync())
+
+		decks, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
+		require.Len(t, deck.Cards, 2)
+		assert.Equal(t, Card{"1", "foo", "foo", []string{"bar"}, "A-row", nil}, deck.Cards[0])
+		assert.Equal(t, Card{"2", "baz", "baz", []string{"qux"}, "K-row", nil}, deck.Cards[1])
+
+		require.Len(t, deck.SubDecks, 2)
+		assert.Equal(t, "A-row", deck.SubDecks[0].Name)
+		assert.Equal(t, RatingTypeSelf, deck.SubDecks[0].RatingType)
+		assert.Equal(t, 10, deck.SubDecks[0].PerReview)
+
+		sub := deck.SubDeck("K-row")
+		require.NotNil(t, sub)
+		assert.Equal(t, RatingTypeSelf, sub.RatingType)
+		assert.Equal(t, 5, sub.PerReview)
+		assert.Equal(t, "0.3", sub.Params["MIN_INTERVAL"])
+		assert.Equal(t, "t", sub.Params["SUBDECKS"])
+		assert.Equal(t, sub.Params, deck.cardParams(deck.Cards[1]))
+		assert.Nil(t, deck.SubDeck("foo"))
+
+		time.Sleep(100 * time.Millisecond)
+		require.NoError(t, ioutil.WriteFile(deckfile.Name(), []byte("* Test\n:PROPERTIES:\n:SUBDECKS: t\n:END:\n** A-row\n:PROPERTIES:\n:ALGORITHM: sm2\n:END:\n"), 0644))
+		err = deck.Reload()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "ALGORITHM")
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		deckfile, err := ioutil.TempFile("", "deck.org")
+		require.NoError(t, err)
+		defer os.Remove(deckfile.Name())
+
+		content := "* Test :japanese:\n:PROPERTIES:\n:SUBDECKS: t\n:END:\n" +
+			"** Verbs :verbs:\n*** 行く :irregular:japanese:\n:PROPERTIES:\n:ID: 1\n:END:\nto go\n*** 食べる\n:PROPERTIES:\n:ID: 2\n:END:\nto eat\n"
+		_, err = deckfile.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, deckfile.Sync())
+
+		decks, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
+		assert.Equal(t, "Test", deck.Name)
+		assert.Equal(t, []string{"japanese"}, deck.Tags)
+		assert.Equal(t, []string{"japanese", "verbs"}, deck.SubDecks[0].Tags)
+		require.Len(t, deck.Cards, 2)
+		assert.Equal(t, "行く", deck.Cards[0].Question)
+		assert.Equal(t, []string{"japanese", "verbs", "irregular"}, deck.Cards[0].Tags)
+		assert.Equal(t, []string{"japanese", "verbs"}, deck.Cards[1].Tags)
+	})
+
+	t.Run("NonASCIITags", func(t *testing.T) {
+		deckfile, err := ioutil.TempFile("", "deck.org")
+		require.NoError(t, err)
+		defer os.Remove(deckfile.Name())
+
+		_, err = deckfile.Write([]byte("* Test\n** 食べる :動詞:\n:PROPERTIES:\n:ID: 1\n:END:\nto eat\n"))
+		require.NoError(t, err)
+		require.NoError(t, deckfile.Sync())
+
+		decks, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks[0].Cards, 1)
+		assert.Equal(t, "食べる :動詞:", decks[0].Cards[0].Question)
+		assert.Empty(t, decks[0].Cards[0].Tags)
+	})
+
+	t.Run("Cloze", func(t *testing.T) {
+		deckfile, err := ioutil.TempFile("", "deck.org")
+		require.NoError(t, err)
+		defer os.Remove(deckfile.Name())
+
+		content := "* Test\n** Sentence\n:PROPERTIES:\n:ID: 1\n:END:\n私は{{c1::学生}}です。{{c2::猫::animal}}が好き。\n** foo\n:PROPERTIES:\n:ID: 2\n:END:\nbar\n"
+		_, err = deckfile.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, deckfile.Sync())
+
+		decks, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
+		require.Len(t, deck.Cards, 3)
+		assert.Equal(t, Card{"1#c1", "私は[...]です。猫が好き。", "私は[...]です。猫が好き。", []string{"学生"}, "", nil}, deck.Cards[0])
+		assert.Equal(t, Card{"1#c2", "私は学生です。[animal]が好き。", "私は学生です。[animal]が好き。", []string{"猫"}, "", nil}, deck.Cards[1])
+		assert.Equal(t, "2", deck.Cards[2].ID)
+
+		decks, err = OpenDeck(deckfile.Name(), OutputFormatHTML)
+		require.NoError(t, err)
+		assert.Contains(t, decks[0].Cards[0].Question, "<p>")
+		assert.Equal(t, "学生", decks[0].Cards[0].Answer())
+	})
+
+	t.Run("Reverse", func(t *testing.T) {
+		deckfile, err := ioutil.TempFile("", "deck.org")
+		require.NoError(t, err)
+		defer os.Remove(deckfile.Name())
+
+		content := "* Test\n:PROPERTIES:\n:REVERSE: t\n:END:\n" +
+			"** あ\n:PROPERTIES:\n:ID: 1\n:END:\na\n" +
+			"** /emphasis/\n:PROPERTIES:\n:ID: 2\n:END:\nfoo\nbar\n" +
+			"** い\n:PROPERTIES:\n:ID: 3\n:REVERSE: nil\n:END:\ni\n"
+		_, err = deckfile.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, deckfile.Sync())
+
+		decks, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
+		assert.True(t, deck.Reverse)
+		require.Len(t, deck.Cards, 5)
+		assert.Equal(t, Card{"1", "あ", "あ", []string{"a"}, "", nil}, deck.Cards[0])
+		assert.Equal(t, Card{"1#r", "a", "a", []string{"あ"}, "", nil}, deck.Cards[1])
+		assert.Equal(t, Card{"2#r", "foo\nbar", "foo\nbar", []string{"/emphasis/"}, "", nil}, deck.Cards[3])
+		assert.Equal(t, "3", deck.Cards[4].ID)
+
+		decks, err = OpenDeck(deckfile.Name(), OutputFormatHTML)
+		require.NoError(t, err)
+		assert.Equal(t, "<p>\nfoo\n</p>\n<p>\nbar\n</p>\n", decks[0].Cards[3].Question)
+		assert.Equal(t, "/emphasis/", decks[0].Cards[3].Answer())
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "decks")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		filename := filepath.Join(dir, "deck.md")
+		content := "---\nrater: self\n---\n# Test\n<!-- ALGORITHM: sm2 -->\n## *foo*\n<!-- ID: 1 -->\nbar\n## baz\nqux\n"
+		require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+		decks, err := OpenDeck(filename, OutputFormatHTML)
+		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
+		assert.Equal(t, "Test", deck.Name)
+		assert.Equal(t, RatingTypeSelf, deck.RatingType)
+		assert.Equal(t, SRSSupermemo2, deck.Algorithm)
+		require.Len(t, deck.Cards, 2)
+		assert.Equal(t, Card{"1", "<em>foo</em>", "/foo/", []string{"bar"}, "", nil}, deck.Cards[0])
+		assert.Len(t, deck.Cards[1].ID, 36)
+		require.NoError(t, deck.WriteIDs())
+
+		data, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(
+			t,
+			"---\nrater: self\n---\n# Test\n<!-- ALGORITHM: sm2 -->\n## *foo*\n<!-- ID: 1 -->\nbar\n## baz\n<!-- ID: "+deck.Cards[1].ID+" -->\nqux\n",
+			string(data),
+		)
+
+		require.NoError(t, deck.SetParams(SRSParams{"PER_REVIEW": "5", "RATER": "auto"}))
+		assert.Equal(t, 5, deck.PerReview)
+		assert.Equal(t, RatingTypeAuto, deck.RatingType)
+		require.Len(t, deck.Cards, 2)
+
+		data, err = ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "# Test\n<!-- ALGORITHM: sm2 -->\n<!-- PER_REVIEW: 5 -->\n<!-- RATER: auto -->\n## *foo*")
+	})
+
+	t.Run("SetParams", func(t *testing.T) {
+		deckfile, err := ioutil.TempFile("", "deck.org")
+		require.NoError(t, err)
+		defer os.Remove(deckfile.Name())
+
+		_, err = deckfile.Write([]byte("* Test\n:PROPERTIES:\n:ALGORITHM:    sm2\n:INITIAL_EASE: 2.2\n:END:\n** foo\n:PROPERTIES:\n:ID: 1\n:END:\nbar\n"))
+		require.NoError(t, err)
+		require.NoError(t, deckfile.Sync())
+
+		decks, err := OpenDeck(deckfile.Name(), OutputFormatOrg)
+		require.NoError(t, err)
+		require.Len(t, decks, 1)
+		deck := decks[0]
+
+		require.NoError(t, deck.SetParams(SRSParams{"INITIAL_EASE": "2.6", "MIN_EASE": "1.5"}))
+		assert.Equal(t, "2.6", deck.Params["INITIAL_EASE"])
+		assert.Equal(t, "1.5", deck.Params["MIN_EASE"])
+		require.Len(t, deck.Cards, 1)
+
+		content, err := ioutil.ReadFile(deckfile.Name())
+		require.NoError(t, err)
+		assert.Equal(t, "* Test\n:PROPERTIES:\n:ALGORITHM:    sm2\n:INITIAL_EASE: 2.6\n:MIN_EASE: 1.5\n:END:\n** foo\n:PROPERTIES:\n:ID: 1\n:END:\nbar\n", string(content))
+
+		require.NoError(t, ioutil.WriteFile(deckfile.Name(), []byte("* Test\n** foo\n:PROPERTIES:\n:ID: 1\n:END:\nbar\n"), 0644))
+		require.NoError(t, deck.SetParams(SRSParams{"PER_REVIEW": "10"}))
+		assert.Equal(t, 10, deck.PerReview)
+
+		content, err = ioutil.ReadFile(deckfile.Name())
+		require.NoError(t, err)
+		assert.Equal(t, "* Test\n:PROPERTIES:\n:PER_REVIEW: 10\n:END:\n** foo\n:PROPERTIES:\n:ID: 1\n:END:\nbar\n", string(content))
+	})
 }
@@ -19,11 +19,48 @@ type Ebisu struct {
 	Beta           float64
 	Interval       float64
 	Historical     []IntervalSnapshot
+
+	clock Clock
+}
+
+// EbisuParams defines initial model of new cards.
+type EbisuParams struct {
+	// Alpha is an alpha parameter of the prior, EBISU_ALPHA property.
+	Alpha float64
+	// Beta is a beta parameter of the prior, EBISU_BETA property.
+	Beta float64
+	// Halflife is an initial half-life in hours, EBISU_HALFLIFE property.
+	Halflife float64
+}
+
+// DefaultEbisuParams returns default Ebisu prior.
+func DefaultEbisuParams() EbisuParams {
+	return EbisuParams{3, 3, 24}
 }
 
-// NewEbisu consturcts a new Ebisu instance.
-func NewEbisu() *Ebisu {
-	return &Ebisu{time.Now().Add(-24 * time.Hour), 3, 3, 24, make([]IntervalSnapshot, 0)}
+// ParseEbisuParams reads EbisuParams from deck parameters, defaults
+// are used for missing values.
+func ParseEbisuParams(params SRSParams) (EbisuParams, error) {
+	res := DefaultEbisuParams()
+	p := &paramsParser{params: params}
+	p.float("EBISU_ALPHA", &res.Alpha, 1, 100)
+	p.float("EBISU_BETA", &res.Beta, 1, 100)
+	p.float("EBISU_HALFLIFE", &res.Halflife, 1, 24*365)
+
+	return res, p.err
+}
+
+// NewEbisu consturcts a new Ebisu instance that uses provided clock
+// and initial model.
+func NewEbisu(clock Clock, params EbisuParams) *Ebisu {
+	return &Ebisu{
+		timeNow(clock).Add(-time.Duration(params.Halflife * float64(time.Hour))),
+		params.Alpha,
+		params.Beta,
+		params.Halflife,
+		make([]IntervalSnapshot, 0),
+		clock,
+	}
 }
 
 // NextReviewAt returns next review timestamp for a card.
@@ -38,18 +75,19 @@ func (eb *Ebisu) Less(other SRSAlgorithm) bool {
 
 // Advance advances supermemo state for a card.
 func (eb *Ebisu) Advance(rating float64) (interval float64) {
+	now := timeNow(eb.clock)
 	model := &model{eb.Alpha, eb.Beta, eb.Interval}
-	elapsed := float64(time.Since(eb.LastReviewedAt)) / float64(time.Hour)
+	elapsed := float64(now.Sub(eb.LastReviewedAt)) / float64(time.Hour)
 	proposed := updateRecall(model, rating >= ratingSuccess, float64(elapsed), true, eb.Interval)
 
 	eb.Historical = append(
 		eb.Historical,
-		IntervalSnapshot{time.Now().Unix(), eb.Interval, 0},
+		IntervalSnapshot{now.Unix(), eb.Interval, 0},
 	)
 	eb.Alpha = proposed.Alpha
 	eb.Beta = proposed.Beta
 	eb.Interval = proposed.T
-	eb.LastReviewedAt = time.Now()
+	eb.LastReviewedAt = now
 	return eb.Interval
 }
 
@@ -87,7 +125,7 @@ func (eb *Ebisu) UnmarshalJSON(b []byte) error {
 }
 
 func (eb *Ebisu) predictRecall() float64 {
-	tnow := float64(time.Since(eb.LastReviewedAt)) / float64(time.Hour)
+	tnow := float64(timeNow(eb.clock).Sub(eb.LastReviewedAt)) / float64(time.Hour)
 	dt := tnow / eb.Interval
 	ret := betaln(eb.Alpha+dt, eb.Beta) - betaln(eb.Alpha, eb.Beta)
 	return math.Exp(ret)
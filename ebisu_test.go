@@ -10,6 +10,7 @@ import (
 )
 
 func TestEbisu(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(100, 0))
 	cases := []struct {
 		model [3]float64
 		op    [2]float64
@@ -30,11 +31,12 @@ func TestEbisu(t *testing.T) {
 	for idx, tc := range cases {
 		t.Run(fmt.Sprintf("Advance %d", idx), func(t *testing.T) {
 			eb := &Ebisu{
-				time.Now().Add(toHourDuration(-1 * tc.op[1])),
-				tc.model[0],
-				tc.model[1],
-				tc.model[2],
-				make([]IntervalSnapshot, 0),
+				LastReviewedAt: clock.Now().Add(toHourDuration(-1 * tc.op[1])),
+				Alpha:          tc.model[0],
+				Beta:           tc.model[1],
+				Interval:       tc.model[2],
+				Historical:     make([]IntervalSnapshot, 0),
+				clock:          clock,
 			}
 			eb.Advance(tc.op[0])
 			assert.InDelta(t, tc.post[0], eb.Alpha, 0.01)
@@ -45,28 +47,37 @@ func TestEbisu(t *testing.T) {
 }
 
 func TestEbisueNextReviewAt(t *testing.T) {
-	srs := NewEbisu()
-	assert.InDelta(t, time.Since(srs.NextReviewAt()), time.Duration(0), float64(time.Minute))
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	srs := NewEbisu(clock, DefaultEbisuParams())
+	assert.Equal(t, clock.Now(), srs.NextReviewAt())
 	interval := srs.Advance(1)
-	assert.InDelta(t, time.Duration(interval)*time.Hour, time.Until(srs.NextReviewAt()), float64(time.Minute))
+	assert.Equal(t, clock.Now().Add(time.Duration(interval)*time.Hour), srs.NextReviewAt())
+}
+
+func TestEbisuParams(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	srs := NewEbisu(clock, EbisuParams{Alpha: 4, Beta: 4, Halflife: 48})
+	assert.Equal(t, clock.Now(), srs.NextReviewAt())
+	assert.Equal(t, clock.Now().Add(-48*time.Hour), srs.LastReviewedAt)
+	assert.Equal(t, 4.0, srs.Alpha)
+	assert.Equal(t, 48.0, srs.Interval)
 }
 
 func TestEbisuRecord(t *testing.T) {
 	results := [][]float64{
-		{1.0, 1.0, 1.0, 0.41, 0.41, 0.41, 0.41, 0.41, 0.41},
-		{1.0, 1.0, 3.06, 3.06, 3.06, 3.06, 3.06, 9.39, 9.39},
-		{1.0, 1.0, 3.06, 3.06, 3.06, 3.06, 3.06, 9.39, 9.39},
+		{1.0, 1.0, 1.13, 0.47, 0.47, 0.47, 0.47, 0.47, 0.47},
+		{1.0, 1.0, 1.0, 3.07, 3.07, 3.07, 3.07, 3.07, 9.40},
+		{1.0, 1.0, 1.0, 3.07, 3.07, 3.07, 3.07, 3.07, 9.40},
 	}
 	for idx, rating := range []float64{0.5, 0.6, 1.0} {
 		t.Run(fmt.Sprintf("%f", rating), func(t *testing.T) {
-			srs := NewEbisu()
+			clock := NewSimulatedClock(time.Unix(100, 0))
+			srs := NewEbisu(clock, DefaultEbisuParams())
 			intervals := []float64{}
 			for i := 0; i < 9; i++ {
 				interval := srs.Advance(rating)
 				intervals = append(intervals, interval/24)
-
-				curInterval := srs.Interval * float64(time.Hour)
-				srs.LastReviewedAt = time.Now().Add(-time.Duration(curInterval))
+				clock.Advance(time.Duration(srs.Interval * float64(time.Hour)))
 			}
 
 			assert.InDeltaSlice(t, results[idx], intervals, 0.01)
@@ -74,31 +85,32 @@ func TestEbisuRecord(t *testing.T) {
 	}
 
 	t.Run("sequence", func(t *testing.T) {
-		srs := NewEbisu()
+		clock := NewSimulatedClock(time.Unix(100, 0))
+		srs := NewEbisu(clock, DefaultEbisuParams())
 		intervals := []float64{}
 		for _, rating := range []float64{1, 1, 1, 0.5, 1, 1, 1} {
 			interval := srs.Advance(rating)
 			intervals = append(intervals, interval/24)
-
-			curInterval := srs.Interval * float64(time.Hour)
-			srs.LastReviewedAt = time.Now().Add(-time.Duration(curInterval))
+			clock.Advance(time.Duration(srs.Interval * float64(time.Hour)))
 		}
 
-		assert.InDeltaSlice(t, []float64{1, 1, 3.06, 1.27, 1.27, 1.27, 3.89}, intervals, 0.01)
+		assert.InDeltaSlice(t, []float64{1, 1, 1, 1, 1, 1, 3.07}, intervals, 0.01)
 	})
 }
 
 func TestEbisuPredictRecall(t *testing.T) {
-	eb := &Ebisu{LastReviewedAt: time.Now().Add(-1 * time.Hour), Alpha: 4, Beta: 4, Interval: 24}
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	eb := &Ebisu{LastReviewedAt: clock.Now().Add(-1 * time.Hour), Alpha: 4, Beta: 4, Interval: 24, clock: clock}
 	assert.InDelta(t, 0.96, eb.predictRecall(), 0.01)
 
-	eb = &Ebisu{LastReviewedAt: time.Now().Add(-1 * time.Hour), Alpha: 2, Beta: 4, Interval: 24}
+	eb = &Ebisu{LastReviewedAt: clock.Now().Add(-1 * time.Hour), Alpha: 2, Beta: 4, Interval: 24, clock: clock}
 	assert.InDelta(t, 0.94, eb.predictRecall(), 0.01)
 }
 
 func TestEbisuLess(t *testing.T) {
-	eb1 := &Ebisu{LastReviewedAt: time.Now().Add(-1 * time.Hour), Alpha: 4, Beta: 4, Interval: 24}
-	eb2 := &Ebisu{LastReviewedAt: time.Now().Add(-1 * time.Hour), Alpha: 2, Beta: 4, Interval: 24}
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	eb1 := &Ebisu{LastReviewedAt: clock.Now().Add(-1 * time.Hour), Alpha: 4, Beta: 4, Interval: 24, clock: clock}
+	eb2 := &Ebisu{LastReviewedAt: clock.Now().Add(-1 * time.Hour), Alpha: 2, Beta: 4, Interval: 24, clock: clock}
 
 	slice := []SRSAlgorithm{eb1, eb2}
 	sort.Slice(slice, func(i, j int) bool { return slice[j].Less(slice[i]) })
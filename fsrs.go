@@ -0,0 +1,233 @@
+package leaf
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+const (
+	fsrsDecay  = -0.5
+	fsrsFactor = 19.0 / 81.0
+)
+
+// fsrsWeights are default FSRS-4.5 model weights.
+var fsrsWeights = [17]float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031,
+	1.6474, 0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+// fsrsGrade defines FSRS review grades. FSRS "hard" grade (2) is not
+// used, ratings below ratingSuccess are failed reviews.
+type fsrsGrade int
+
+const (
+	fsrsGradeAgain fsrsGrade = 1
+	fsrsGradeGood  fsrsGrade = 3
+	fsrsGradeEasy  fsrsGrade = 4
+)
+
+// FSRS calculates review intervals using Free Spaced Repetition
+// Scheduler (FSRS-4.5) algorithm. Stability is expressed in days and
+// defines interval for which retrievability drops to 90%, Difficulty
+// is within [1, 10] range.
+type FSRS struct {
+	LastReviewedAt time.Time
+	Stability      float64
+	Difficulty     float64
+	Interval       float64
+	Reps           int
+	Lapses         int
+	Historical     []IntervalSnapshot
+
+	clock  Clock
+	params FSRSParams
+}
+
+// FSRSParams defines tunable parameters of FSRS scheduler.
+type FSRSParams struct {
+	// RequestRetention is a desired probability of recall at the
+	// time of the review, DESIRED_RETENTION property.
+	RequestRetention float64
+	// MaxInterval is an upper bound of intervals in days,
+	// MAX_INTERVAL property.
+	MaxInterval float64
+}
+
+// DefaultFSRSParams returns default FSRS scheduler parameters.
+func DefaultFSRSParams() FSRSParams {
+	return FSRSParams{0.9, 36500}
+}
+
+// ParseFSRSParams reads FSRSParams from deck parameters, defaults are
+// used for missing values.
+func ParseFSRSParams(params SRSParams) (FSRSParams, error) {
+	res := DefaultFSRSParams()
+	p := &paramsParser{params: params}
+	p.float("DESIRED_RETENTION", &res.RequestRetention, 0.7, 0.99)
+	p.float("MAX_INTERVAL", &res.MaxInterval, 1, math.Inf(1))
+
+	return res, p.err
+}
+
+// NewFSRS returns a new FSRS instance that uses provided clock and
+// parameters.
+func NewFSRS(clock Clock, params FSRSParams) *FSRS {
+	return &FSRS{
+		LastReviewedAt: timeNow(clock),
+		Stability:      0,
+		Difficulty:     0,
+		Interval:       0,
+		Historical:     make([]IntervalSnapshot, 0),
+		clock:          clock,
+		params:         params,
+	}
+}
+
+// NextReviewAt returns next review timestamp for a card.
+func (fs *FSRS) NextReviewAt() time.Time {
+	return fs.LastReviewedAt.Add(time.Duration(24*fs.Interval) * time.Hour)
+}
+
+// Less defines card order for the review.
+func (fs *FSRS) Less(other SRSAlgorithm) bool {
+	return fs.Retrievability() > other.(*FSRS).Retrievability()
+}
+
+// Retrievability returns probability of recall for a card at the
+// current moment. Cards that were never reviewed have 0
+// retrievability.
+func (fs *FSRS) Retrievability() float64 {
+	if fs.Stability <= 0 {
+		return 0
+	}
+
+	elapsed := timeNow(fs.clock).Sub(fs.LastReviewedAt).Hours() / 24
+	return math.Pow(1+fsrsFactor*math.Max(0, elapsed)/fs.Stability, fsrsDecay)
+}
+
+// Advance advances FSRS state for a card. Rating is converted to a
+// grade: failed reviews are graded as "again", ratings below 0.9 as
+// "good" and the rest as "easy".
+func (fs *FSRS) Advance(rating float64) float64 {
+	grade := toFSRSGrade(rating)
+
+	if fs.Stability <= 0 {
+		fs.Stability = fsrsInitialStability(grade)
+		fs.Difficulty = fsrsInitialDifficulty(grade)
+	} else {
+		retrievability := fs.Retrievability()
+		fs.Difficulty = fsrsNextDifficulty(fs.Difficulty, grade)
+		if grade == fsrsGradeAgain {
+			fs.Stability = fsrsForgetStability(fs.Difficulty, fs.Stability, retrievability)
+		} else {
+			fs.Stability = fsrsRecallStability(fs.Difficulty, fs.Stability, retrievability, grade)
+		}
+	}
+
+	fs.Reps++
+	if grade == fsrsGradeAgain {
+		fs.Lapses++
+	}
+
+	now := timeNow(fs.clock)
+	fs.LastReviewedAt = now
+	if fs.Historical == nil {
+		fs.Historical = make([]IntervalSnapshot, 0)
+	}
+	fs.Historical = append(
+		fs.Historical,
+		IntervalSnapshot{now.Unix(), fs.Interval, fs.Difficulty},
+	)
+
+	interval := fs.Stability / fsrsFactor * (math.Pow(fs.params.RequestRetention, 1/fsrsDecay) - 1)
+	fs.Interval = math.Min(interval, fs.params.MaxInterval)
+	return fs.Interval
+}
+
+// MarshalJSON implements json.Marshaller for FSRS
+func (fs *FSRS) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		LastReviewedAt time.Time
+		Stability      float64
+		Difficulty     float64
+		Interval       float64
+		Reps           int
+		Lapses         int
+		Historical     []IntervalSnapshot
+	}{fs.LastReviewedAt, fs.Stability, fs.Difficulty, fs.Interval, fs.Reps, fs.Lapses, fs.Historical})
+}
+
+// UnmarshalJSON implements json.Unmarshaller for FSRS
+func (fs *FSRS) UnmarshalJSON(b []byte) error {
+	payload := &struct {
+		LastReviewedAt time.Time
+		Stability      float64
+		Difficulty     float64
+		Interval       float64
+		Reps           int
+		Lapses         int
+		Historical     []IntervalSnapshot
+	}{}
+
+	if err := json.Unmarshal(b, payload); err != nil {
+		return err
+	}
+
+	fs.LastReviewedAt = payload.LastReviewedAt
+	fs.Stability = payload.Stability
+	fs.Difficulty = payload.Difficulty
+	fs.Interval = payload.Interval
+	fs.Reps = payload.Reps
+	fs.Lapses = payload.Lapses
+	fs.Historical = payload.Historical
+	return nil
+}
+
+func toFSRSGrade(rating float64) fsrsGrade {
+	switch {
+	case rating < ratingSuccess:
+		return fsrsGradeAgain
+	case rating < 0.9:
+		return fsrsGradeGood
+	default:
+		return fsrsGradeEasy
+	}
+}
+
+func fsrsInitialStability(grade fsrsGrade) float64 {
+	return math.Max(fsrsWeights[grade-1], 0.1)
+}
+
+func fsrsInitialDifficulty(grade fsrsGrade) float64 {
+	d := fsrsWeights[4] - float64(grade-3)*fsrsWeights[5]
+	return math.Max(1, math.Min(10, d))
+}
+
+func fsrsNextDifficulty(d float64, grade fsrsGrade) float64 {
+	next := d - fsrsWeights[6]*float64(grade-3)
+	// mean reversion towards initial difficulty of a "good" grade
+	next = fsrsWeights[7]*fsrsInitialDifficulty(fsrsGradeGood) + (1-fsrsWeights[7])*next
+	return math.Max(1, math.Min(10, next))
+}
+
+func fsrsRecallStability(d, s, r float64, grade fsrsGrade) float64 {
+	easyBonus := 1.0
+	if grade == fsrsGradeEasy {
+		easyBonus = fsrsWeights[16]
+	}
+
+	return s * (1 + math.Exp(fsrsWeights[8])*
+		(11-d)*
+		math.Pow(s, -fsrsWeights[9])*
+		(math.Exp((1-r)*fsrsWeights[10])-1)*
+		easyBonus)
+}
+
+func fsrsForgetStability(d, s, r float64) float64 {
+	next := fsrsWeights[11] *
+		math.Pow(d, -fsrsWeights[12]) *
+		(math.Pow(s+1, fsrsWeights[13]) - 1) *
+		math.Exp((1-r)*fsrsWeights[14])
+	return math.Max(0.1, math.Min(next, s))
+}
@@ -0,0 +1,111 @@
+package leaf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFSRSNextReviewAt(t *testing.T) {
+	fs := &FSRS{LastReviewedAt: time.Unix(100, 0), Interval: 1}
+	assert.Equal(t, int64(86500), fs.NextReviewAt().Unix())
+
+	fs = &FSRS{LastReviewedAt: time.Unix(100, 0).Add(-24 * time.Hour), Interval: 1}
+	assert.Equal(t, int64(100), fs.NextReviewAt().Unix())
+
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	fs = NewFSRS(clock, DefaultFSRSParams())
+	assert.Equal(t, clock.Now(), fs.NextReviewAt())
+	interval := fs.Advance(1)
+	assert.Equal(t, clock.Now().Add(time.Duration(24*interval)*time.Hour), fs.NextReviewAt())
+}
+
+func TestFSRSRetrievability(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	fs := &FSRS{LastReviewedAt: clock.Now().Add(-24 * time.Hour), Stability: 1, clock: clock}
+	assert.InDelta(t, 0.9, fs.Retrievability(), 0.01)
+
+	fs = &FSRS{LastReviewedAt: clock.Now().Add(-240 * time.Hour), Stability: 1, clock: clock}
+	assert.InDelta(t, 0.54, fs.Retrievability(), 0.01)
+
+	fs = NewFSRS(clock, DefaultFSRSParams())
+	assert.InDelta(t, 0, fs.Retrievability(), 0.01)
+}
+
+func TestFSRSLess(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	fs1 := &FSRS{LastReviewedAt: clock.Now().Add(-time.Hour), Stability: 1, clock: clock}
+	fs2 := &FSRS{LastReviewedAt: clock.Now().Add(-48 * time.Hour), Stability: 1, clock: clock}
+
+	slice := []SRSAlgorithm{fs1, fs2}
+	sort.Slice(slice, func(i, j int) bool { return slice[j].Less(slice[i]) })
+	assert.Equal(t, []SRSAlgorithm{fs2, fs1}, slice)
+}
+
+func TestFSRSRecord(t *testing.T) {
+	results := [][]float64{
+		{0.49, 0.28, 0.17, 0.11, 0.1, 0.1, 0.1, 0.1, 0.1},
+		{3.71, 14.09, 46.92, 139.63, 377.30, 937.92, 2168.46, 4705.47, 9657.46},
+		{13.82, 139.39, 1156.93, 8048.19, 36500, 36500, 36500, 36500, 36500},
+	}
+	for idx, rating := range []float64{0.5, 0.6, 1.0} {
+		t.Run(fmt.Sprintf("%f", rating), func(t *testing.T) {
+			clock := NewSimulatedClock(time.Unix(100, 0))
+			fs := NewFSRS(clock, DefaultFSRSParams())
+			intervals := []float64{}
+			for i := 0; i < 9; i++ {
+				interval := fs.Advance(rating)
+				intervals = append(intervals, interval)
+				clock.Advance(time.Duration(fs.Interval * 24 * float64(time.Hour)))
+			}
+
+			assert.InDeltaSlice(t, results[idx], intervals, 0.01)
+		})
+	}
+
+	t.Run("sequence", func(t *testing.T) {
+		clock := NewSimulatedClock(time.Unix(100, 0))
+		fs := NewFSRS(clock, DefaultFSRSParams())
+		intervals := []float64{}
+		for _, rating := range []float64{1, 1, 1, 1, 0.5, 1} {
+			interval := fs.Advance(rating)
+			intervals = append(intervals, interval)
+			clock.Advance(time.Duration(fs.Interval * 24 * float64(time.Hour)))
+		}
+
+		assert.InDeltaSlice(t, []float64{13.82, 139.39, 1156.93, 8048.19, 39.31, 370.41}, intervals, 0.01)
+
+		historical := []float64{}
+		for _, snap := range fs.Historical {
+			assert.NotNil(t, snap.Timestamp)
+			historical = append(historical, snap.Interval)
+		}
+		assert.InDeltaSlice(t, []float64{0, 13.82, 139.39, 1156.93, 8048.19, 39.31}, historical, 0.01)
+		assert.Equal(t, 6, fs.Reps)
+		assert.Equal(t, 1, fs.Lapses)
+	})
+}
+
+func TestFSRSParams(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	fs := NewFSRS(clock, FSRSParams{RequestRetention: 0.8, MaxInterval: 36500})
+	assert.InDelta(t, 33.14, fs.Advance(1), 0.01)
+
+	fs = NewFSRS(clock, FSRSParams{RequestRetention: 0.9, MaxInterval: 10})
+	assert.InDelta(t, 10, fs.Advance(1), 0.01)
+}
+
+func TestFSRSJsonMarshalling(t *testing.T) {
+	fs := &FSRS{LastReviewedAt: time.Unix(100, 0).UTC(), Stability: 2, Difficulty: 5, Interval: 2, Reps: 1}
+	res, err := json.Marshal(fs)
+	require.NoError(t, err)
+
+	newFS := new(FSRS)
+	require.NoError(t, json.Unmarshal(res, newFS))
+	assert.Equal(t, fs, newFS)
+}
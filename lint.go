@@ -0,0 +1,457 @@
+package leaf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/niklasfasching/go-org/org"
+)
+
+// Diagnostic describes a problem found in a deck file. Warnings
+// describe supported but likely unintended cases.
+type Diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+	Warning bool   `json:"warning,omitempty"`
+}
+
+// String returns diagnostic in a "file:line: message" format,
+// warnings are prefixed with "warning:".
+func (d Diagnostic) String() string {
+	if d.Warning {
+		return fmt.Sprintf("%s:%d: warning: %s", d.File, d.Line, d.Message)
+	}
+
+	return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+}
+
+var (
+	orgLinkRegexp  = regexp.MustCompile(`\[\[(?:file:)?([^\]]+)\](?:\[[^\]]*\])?\]`)
+	mdImageRegexp  = regexp.MustCompile(`!\[[^\]]*\]\(([^)\s]+)`)
+	imageExtRegexp = regexp.MustCompile(`(?i)\.(?:png|gif|jpe?g|svg|webp|bmp|tiff?)$`)
+
+	// known properties of decks, sub-decks and cards, other
+	// properties of decks and sub-decks are algorithm parameters.
+	deckProperties = []string{"ID", "RATER", "ALGORITHM", "PER_REVIEW", "SUBDECKS", "REVERSE"}
+	subProperties  = []string{"ID", "RATER", "ALGORITHM", "PER_REVIEW", "REVERSE"}
+	cardProperties = []string{"ID", "REVERSE"}
+	boolProperties = []string{"SUBDECKS", "REVERSE"}
+)
+
+// ValidateDeck checks decks of an org or Markdown file and returns
+// found problems: duplicate IDs, cards without answers, source
+// blocks without an answer, unknown properties, invalid property
+// values and broken image links. Duplicate questions are reported as
+// warnings. Links to /images/ are resolved using provided deck roots,
+// directory of the file is used if roots are not provided. File is
+// never modified.
+func ValidateDeck(filename string, roots []string) ([]Diagnostic, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("file: %s", err)
+	}
+
+	lines := strings.Split(string(content), "\n")
+	v := &validator{filename: filename, lines: lines, markdown: isMarkdown(filename)}
+	v.headlines = syntaxOf(filename).headlines(lines)
+
+	decks, _, err := parseFile(filename)
+	if err != nil {
+		v.report(1, "%s", err)
+		return v.result, nil
+	}
+
+	for _, root := range decks {
+		v.deck(root)
+	}
+
+	if len(roots) == 0 {
+		roots = []string{filepath.Dir(filename)}
+	}
+	v.images(roots)
+
+	return v.result, nil
+}
+
+// validator collects diagnostics of a single file.
+type validator struct {
+	filename  string
+	lines     []string
+	markdown  bool
+	headlines []int
+	result    []Diagnostic
+}
+
+// deckScope tracks questions and IDs of a deck.
+type deckScope struct {
+	algorithm SRS
+	reverse   bool
+	questions map[string]int
+	ids       map[string]int
+	cards     int
+}
+
+func (v *validator) report(line int, format string, args ...interface{}) {
+	v.result = append(v.result, Diagnostic{v.filename, line, fmt.Sprintf(format, args...), false})
+}
+
+func (v *validator) warn(line int, format string, args ...interface{}) {
+	v.result = append(v.result, Diagnostic{v.filename, line, fmt.Sprintf(format, args...), true})
+}
+
+func (v *validator) deck(root org.Headline) {
+	name := org.String(root.Title)
+	line := v.line(root)
+	scope := &deckScope{
+		algorithm: SRSSupermemo2PlusCustom,
+		questions: make(map[string]int),
+		ids:       make(map[string]int),
+	}
+
+	nested := false
+	if root.Properties != nil {
+		if algo, ok := root.Properties.Get("ALGORITHM"); ok {
+			scope.algorithm = SRS(algo)
+		}
+		if value, ok := root.Properties.Get("SUBDECKS"); ok {
+			nested, _ = strconv.ParseBool(value)
+		}
+		if value, ok := root.Properties.Get("REVERSE"); ok {
+			scope.reverse, _ = strconv.ParseBool(value)
+		}
+	}
+
+	algoValid := true
+	if _, err := NewStats(scope.algorithm, SystemClock, nil); err != nil {
+		algoValid = false
+		v.report(v.propertyLine(root, "ALGORITHM"), "deck %s: %s", name, err)
+	}
+	v.properties(root, scope.algorithm, algoValid, deckProperties)
+
+	for _, node := range root.Children {
+		headline, ok := node.(org.Headline)
+		if !ok {
+			continue
+		}
+
+		if !nested {
+			v.card(headline, scope, scope.reverse)
+			continue
+		}
+
+		reverse := scope.reverse
+		if _, ok := headline.Properties.Get("ALGORITHM"); ok {
+			v.report(v.propertyLine(headline, "ALGORITHM"), "sub-deck %s/%s: ALGORITHM can only be defined for a deck", name, org.String(headline.Title))
+		}
+		if value, ok := headline.Properties.Get("REVERSE"); ok {
+			reverse, _ = strconv.ParseBool(value)
+		}
+		v.properties(headline, scope.algorithm, algoValid, subProperties)
+
+		for _, child := range headline.Children {
+			if cardHeadline, ok := child.(org.Headline); ok {
+				v.card(cardHeadline, scope, reverse)
+			}
+		}
+	}
+
+	if scope.cards == 0 {
+		v.report(line, "deck %s has no cards", name)
+	}
+}
+
+// properties checks that properties of a headline are known and have
+// valid values. Properties that are not in the known list should be
+// parameters of the algorithm.
+func (v *validator) properties(headline org.Headline, srs SRS, algoValid bool, known []string) {
+	if headline.Properties == nil {
+		return
+	}
+
+	for _, prop := range headline.Properties.Properties {
+		name, value := prop[0], prop[1]
+		line := v.propertyLine(headline, name)
+
+		switch {
+		case name == "RATER":
+			if rater := RatingType(value); rater != RatingTypeAuto && rater != RatingTypeSelf {
+				v.report(line, "invalid RATER value %q, supported values are auto and self", value)
+			}
+		case name == "PER_REVIEW":
+			if c, err := strconv.Atoi(value); err != nil || c < 0 {
+				v.report(line, "invalid PER_REVIEW value %q, should be a non-negative number", value)
+			}
+		case hasProperty(boolProperties, name):
+			if _, err := strconv.ParseBool(value); err != nil {
+				v.report(line, "invalid %s value %q, should be t or f", name, value)
+			}
+		case hasProperty(known, name) || !algoValid:
+		case !hasProperty(lookupSRSParams(srs), name):
+			v.report(line, "unknown property %s", name)
+		default:
+			if _, err := NewStats(srs, SystemClock, SRSParams{name: value}); err != nil {
+				v.report(line, "%s", err)
+			}
+		}
+	}
+}
+
+func hasProperty(props []string, name string) bool {
+	for _, prop := range props {
+		if prop == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *validator) card(headline org.Headline, scope *deckScope, reverse bool) {
+	line := v.line(headline)
+	title := org.String(headline.Title)
+
+	if headline.Properties != nil {
+		for _, prop := range headline.Properties.Properties {
+			propLine := v.propertyLine(headline, prop[0])
+			switch {
+			case prop[0] == "REVERSE":
+				if _, err := strconv.ParseBool(prop[1]); err != nil {
+					v.report(propLine, "invalid REVERSE value %q, should be t or f", prop[1])
+				}
+			case !hasProperty(cardProperties, prop[0]):
+				v.report(propLine, "unknown property %s", prop[0])
+			}
+		}
+
+		if value, ok := headline.Properties.Get("REVERSE"); ok {
+			reverse, _ = strconv.ParseBool(value)
+		}
+		if id, ok := headline.Properties.Get("ID"); ok && id != "" {
+			if first, ok := scope.ids[id]; ok {
+				v.report(v.propertyLine(headline, "ID"), "duplicate ID %s, first defined on line %d", id, first)
+			} else {
+				scope.ids[id] = v.propertyLine(headline, "ID")
+			}
+		}
+	}
+
+	if len(headline.Children) == 0 {
+		v.report(line, "card %q has no answer", title)
+		return
+	}
+
+	question, children := title, headline.Children
+	if block, ok := children[0].(org.Block); ok && block.Name == "SRC" {
+		question += "\n" + strings.TrimSpace(org.String([]org.Node{block}))
+		children = children[1:]
+	}
+
+	answers := strings.TrimSpace(org.String(children))
+	if clozes := parseClozes(strings.TrimSpace(org.String(headline.Children))); clozes != nil {
+		for _, c := range clozes {
+			v.question(c.question, line, scope)
+		}
+		return
+	}
+
+	if answers == "" {
+		if len(children) < len(headline.Children) {
+			v.report(line, "source block of card %q has no answer", title)
+		} else {
+			v.report(line, "card %q has no answer", title)
+		}
+		return
+	}
+
+	v.question(question, line, scope)
+	if reverse {
+		v.question(answers, line, scope)
+	}
+}
+
+// question records a card question and warns about duplicates, such
+// cards are reviewed separately but are easy to confuse.
+func (v *validator) question(question string, line int, scope *deckScope) {
+	scope.cards++
+	if first, ok := scope.questions[question]; ok {
+		v.warn(line, "duplicate question %q, first defined on line %d", question, first)
+		return
+	}
+
+	scope.questions[question] = line
+}
+
+// images reports links to missing local images.
+func (v *validator) images(roots []string) {
+	dir := filepath.Dir(v.filename)
+	inBlock, fence := false, ""
+	for idx, line := range v.lines {
+		var links [][]string
+		if v.markdown {
+			if m := mdFenceRegexp.FindStringSubmatch(line); m != nil {
+				if fence == "" {
+					fence = m[1]
+				} else if strings.HasPrefix(strings.TrimSpace(line), fence) {
+					fence = ""
+				}
+				continue
+			}
+			if fence != "" {
+				continue
+			}
+			links = mdImageRegexp.FindAllStringSubmatch(line, -1)
+		} else {
+			switch {
+			case inBlock:
+				inBlock = !endBlockRegexp.MatchString(line)
+				continue
+			case beginBlockRegexp.MatchString(line):
+				inBlock = true
+				continue
+			}
+			links = orgLinkRegexp.FindAllStringSubmatch(line, -1)
+		}
+
+		for _, link := range links {
+			target := link[1]
+			if strings.Contains(target, "://") || !imageExtRegexp.MatchString(target) {
+				continue
+			}
+
+			if !imageExists(target, dir, roots) {
+				v.report(idx+1, "broken image link %s", target)
+			}
+		}
+	}
+}
+
+// imageExists reports whether a linked image exists. Links to
+// /images/ are served from deck roots, relative links are resolved
+// against the deck file directory.
+func imageExists(target, dir string, roots []string) bool {
+	candidates := []string{}
+	switch {
+	case strings.HasPrefix(target, "/images/"):
+		for _, root := range roots {
+			candidates = append(candidates, filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(target, "/images/"))))
+		}
+	case filepath.IsAbs(target):
+		candidates = append(candidates, target)
+	default:
+		candidates = append(candidates, filepath.Join(dir, filepath.FromSlash(target)))
+	}
+
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// line returns 1-based line number of a headline.
+func (v *validator) line(headline org.Headline) int {
+	if headline.Index < 1 || headline.Index > len(v.headlines) {
+		return 1
+	}
+
+	return v.headlines[headline.Index-1] + 1
+}
+
+// propertyLine returns 1-based line number of a headline property,
+// line of the headline is returned for inherited properties.
+func (v *validator) propertyLine(headline org.Headline, name string) int {
+	start := v.line(headline)
+	end := len(v.lines)
+	if headline.Index < len(v.headlines) {
+		end = v.headlines[headline.Index]
+	}
+
+	for idx := start; idx < end && idx < len(v.lines); idx++ {
+		line := strings.TrimSpace(v.lines[idx])
+		line = strings.TrimSpace(strings.TrimPrefix(line, "<!--"))
+		line = strings.TrimPrefix(line, ":")
+		if strings.HasPrefix(strings.ToUpper(line), name+":") {
+			return idx + 1
+		}
+	}
+
+	return start
+}
+
+// Validate checks all deck files of a source, decks defined in
+// multiple files are reported as well.
+func (source DeckSource) Validate() ([]Diagnostic, error) {
+	files, err := source.files()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Diagnostic, 0)
+	paths := make(map[string]string)
+	for _, file := range files {
+		diagnostics, err := ValidateDeck(file.path, source.Roots)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, diagnostics...)
+
+		decks, err := openDeckHeadlines(file.path)
+		if err != nil {
+			continue
+		}
+
+		names := make([]string, 0, len(decks))
+		for name := range decks {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return decks[names[i]] < decks[names[j]] })
+
+		for _, name := range names {
+			line := decks[name]
+			if file.namespace != "" {
+				name = file.namespace + "/" + name
+			}
+
+			if path, ok := paths[name]; ok {
+				result = append(result, Diagnostic{file.path, line, fmt.Sprintf("deck %s is already defined in %s", name, path), false})
+				continue
+			}
+			paths[name] = file.path
+		}
+	}
+
+	return result, nil
+}
+
+// openDeckHeadlines returns line numbers of decks in a file keyed by
+// deck title.
+func openDeckHeadlines(filename string) (map[string]int, error) {
+	decks, _, err := parseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("file: %s", err)
+	}
+
+	v := &validator{lines: strings.Split(string(content), "\n")}
+	v.headlines = syntaxOf(filename).headlines(v.lines)
+
+	result := make(map[string]int, len(decks))
+	for _, root := range decks {
+		result[org.String(root.Title)] = v.line(root)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,135 @@
+package leaf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDeck(t *testing.T) {
+	t.Run("Fixtures", func(t *testing.T) {
+		for _, file := range []string{"./fixtures/hiragana.org", "./fixtures/org-mode.org"} {
+			diagnostics, err := ValidateDeck(file, []string{"./fixtures"})
+			require.NoError(t, err)
+			assert.Empty(t, diagnostics, file)
+		}
+	})
+
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("Org", func(t *testing.T) {
+		content := `* Deck
+:PROPERTIES:
+:RATER: manual
+:PER_REVIEW: many
+:INITIAL_DIFFICULTY: 2
+:FOO: bar
+:END:
+** Question 1
+:PROPERTIES:
+:ID: 1
+:END:
+Answer 1
+** Question 1
+:PROPERTIES:
+:ID: 1
+:CREATED: today
+:END:
+Answer 2
+** Question 2
+** Code
+#+BEGIN_SRC go
+fmt.Println("foo")
+#+END_SRC
+** [[/images/missing.png]]
+[[./200.png][image]]
+* Empty
+:PROPERTIES:
+:ALGORITHM: foo
+:END:
+`
+		filename := filepath.Join(dir, "deck.org")
+		require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+		diagnostics, err := ValidateDeck(filename, nil)
+		require.NoError(t, err)
+
+		messages := make([]string, len(diagnostics))
+		for idx, d := range diagnostics {
+			messages[idx] = d.String()
+		}
+
+		assert.Equal(t, []string{
+			filename + `:3: invalid RATER value "manual", supported values are auto and self`,
+			filename + `:4: invalid PER_REVIEW value "many", should be a non-negative number`,
+			filename + `:5: params: INITIAL_DIFFICULTY should be within [0, 1] range, got 2`,
+			filename + `:6: unknown property FOO`,
+			filename + `:16: unknown property CREATED`,
+			filename + `:15: duplicate ID 1, first defined on line 10`,
+			filename + `:13: warning: duplicate question "Question 1", first defined on line 8`,
+			filename + `:19: card "Question 2" has no answer`,
+			filename + `:20: source block of card "Code" has no answer`,
+			filename + `:28: deck Empty: srs: unknown algorithm "foo"`,
+			filename + `:26: deck Empty has no cards`,
+			filename + `:24: broken image link /images/missing.png`,
+			filename + `:25: broken image link ./200.png`,
+		}, messages)
+
+		written, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(written))
+	})
+
+	t.Run("AlgorithmParams", func(t *testing.T) {
+		content := "* Deck\n:PROPERTIES:\n:ALGORITHM: sm2\n:MIN_EASE: 1.5\n:EBISU_ALPHA: 3\n:DIFFICULTY_RATE: 2\n:END:\n** Question\nAnswer\n"
+		filename := filepath.Join(dir, "params.org")
+		require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+		diagnostics, err := ValidateDeck(filename, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []Diagnostic{
+			{filename, 5, "unknown property EBISU_ALPHA", false},
+			{filename, 6, "unknown property DIFFICULTY_RATE", false},
+		}, diagnostics)
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		content := "# Deck\n<!-- REVERSE: t -->\n## Question 1\nfoo\n## Question 2\nfoo\n"
+		filename := filepath.Join(dir, "deck.md")
+		require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+
+		diagnostics, err := ValidateDeck(filename, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []Diagnostic{{filename, 5, `duplicate question "foo", first defined on line 3`, true}}, diagnostics)
+	})
+}
+
+func TestDeckSourceValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "leaf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := "* Deck\n** Question\nAnswer\n"
+	b := "# Deck\n## Question\nAnswer\n"
+	for _, name := range []string{"E", "D", "C", "B", "A"} {
+		a += "* " + name + "\n** Question\nAnswer\n"
+		b += "# " + name + "\n## Question\nAnswer\n"
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.org"), []byte(a), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.md"), []byte(b), 0644))
+
+	diagnostics, err := DeckSource{Roots: []string{dir}}.Validate()
+	require.NoError(t, err)
+
+	expected := []Diagnostic{}
+	for idx, name := range []string{"Deck", "E", "D", "C", "B", "A"} {
+		expected = append(expected, Diagnostic{filepath.Join(dir, "b.md"), idx*3 + 1, "deck " + name + " is already defined in " + filepath.Join(dir, "a.org"), false})
+	}
+	assert.Equal(t, expected, diagnostics)
+}
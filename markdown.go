@@ -0,0 +1,321 @@
+package leaf
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// markdownExtensions lists file extensions of Markdown decks.
+var markdownExtensions = []string{".md", ".markdown"}
+
+var (
+	mdHeadingRegexp  = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	mdFenceRegexp    = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([^`\\s]*)")
+	mdPropertyRegexp = regexp.MustCompile(`^<!--\s*([A-Za-z0-9_-]+)\s*:\s*(.*?)\s*-->\s*$`)
+	mdKeyValueRegexp = regexp.MustCompile(`^\s*([A-Za-z0-9_-]+)\s*:\s*(.*?)\s*$`)
+	mdBulletRegexp   = regexp.MustCompile(`^(\s*)[*+](\s+)`)
+	mdQuoteRegexp    = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdRuleRegexp     = regexp.MustCompile(`^\s*(?:-{3,}|\*{3,}|_{3,})\s*$`)
+	mdInlineRegexp   = regexp.MustCompile("`([^`]+)`" +
+		`|!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)` +
+		`|\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdEmphasisRegexp = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__` +
+		`|\*([^*\s](?:[^*]*[^*\s])?)\*|_([^_\s](?:[^_]*[^_\s])?)_|~~([^~]+)~~`)
+)
+
+// isMarkdown reports whether a file is a Markdown deck.
+func isMarkdown(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, e := range markdownExtensions {
+		if ext == e {
+			return true
+		}
+	}
+
+	return false
+}
+
+// markdownToOrg converts Markdown deck into an org document. Headings
+// are converted to headlines, HTML comments with a key and a value
+// (e.g. <!-- ID: 1 -->) and "properties" fenced blocks right after a
+// heading define headline properties. Front matter properties are
+// inherited by all top level headlines.
+func markdownToOrg(text string) string {
+	lines := strings.Split(text, "\n")
+	front, idx := markdownFrontMatter(lines)
+
+	out := make([]string, 0, len(lines))
+	for idx < len(lines) {
+		line := lines[idx]
+
+		if m := mdFenceRegexp.FindStringSubmatch(line); m != nil {
+			end := markdownFenceEnd(lines, idx, m[1])
+			if m[2] == "" {
+				out = append(out, "#+BEGIN_EXAMPLE")
+			} else {
+				out = append(out, "#+BEGIN_SRC "+m[2])
+			}
+			out = append(out, lines[idx+1:end]...)
+			if m[2] == "" {
+				out = append(out, "#+END_EXAMPLE")
+			} else {
+				out = append(out, "#+END_SRC")
+			}
+			idx = end + 1
+			continue
+		}
+
+		if m := mdHeadingRegexp.FindStringSubmatch(line); m != nil {
+			out = append(out, strings.Repeat("*", len(m[1]))+" "+markdownInline(m[2]))
+
+			var props [][2]string
+			if len(m[1]) == 1 {
+				props = append(props, front...)
+			}
+
+			var own [][2]string
+			own, idx = markdownProperties(lines, idx+1)
+			for _, prop := range own {
+				props = setMarkdownProp(props, prop[0], prop[1])
+			}
+
+			if len(props) > 0 {
+				out = append(out, ":PROPERTIES:")
+				for _, prop := range props {
+					out = append(out, ":"+prop[0]+": "+prop[1])
+				}
+				out = append(out, ":END:")
+			}
+			continue
+		}
+
+		if mdQuoteRegexp.MatchString(line) {
+			out = append(out, "#+BEGIN_QUOTE")
+			for ; idx < len(lines); idx++ {
+				m := mdQuoteRegexp.FindStringSubmatch(lines[idx])
+				if m == nil {
+					break
+				}
+				out = append(out, markdownInline(m[1]))
+			}
+			out = append(out, "#+END_QUOTE")
+			continue
+		}
+
+		if mdRuleRegexp.MatchString(line) {
+			out = append(out, "-----")
+			idx++
+			continue
+		}
+
+		line = mdBulletRegexp.ReplaceAllString(line, "$1-$2")
+		out = append(out, markdownInline(line))
+		idx++
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// markdownFrontMatter returns properties defined in a YAML front
+// matter along with index of the first line after it. Only "key:
+// value" pairs are supported.
+func markdownFrontMatter(lines []string) ([][2]string, int) {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil, 0
+	}
+
+	var props [][2]string
+	for idx := 1; idx < len(lines); idx++ {
+		line := strings.TrimSpace(lines[idx])
+		if line == "---" || line == "..." {
+			return props, idx + 1
+		}
+
+		if m := mdKeyValueRegexp.FindStringSubmatch(line); m != nil {
+			props = setMarkdownProp(props, m[1], strings.Trim(m[2], `"'`))
+		}
+	}
+
+	return nil, 0
+}
+
+// markdownProperties returns properties defined right after a
+// heading starting from a given line along with index of the first
+// line after them.
+func markdownProperties(lines []string, idx int) ([][2]string, int) {
+	var props [][2]string
+	for idx < len(lines) {
+		if m := mdPropertyRegexp.FindStringSubmatch(lines[idx]); m != nil {
+			props = setMarkdownProp(props, m[1], m[2])
+			idx++
+			continue
+		}
+
+		if m := mdFenceRegexp.FindStringSubmatch(lines[idx]); m != nil && m[2] == "properties" {
+			end := markdownFenceEnd(lines, idx, m[1])
+			for _, line := range lines[idx+1 : end] {
+				if kv := mdKeyValueRegexp.FindStringSubmatch(line); kv != nil {
+					props = setMarkdownProp(props, kv[1], kv[2])
+				}
+			}
+			idx = end + 1
+			continue
+		}
+
+		break
+	}
+
+	return props, idx
+}
+
+// markdownFenceEnd returns index of a line closing a fenced block
+// that starts at a given line. Unclosed blocks end with the file.
+func markdownFenceEnd(lines []string, start int, fence string) int {
+	for idx := start + 1; idx < len(lines); idx++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[idx]), fence) {
+			return idx
+		}
+	}
+
+	return len(lines)
+}
+
+// setMarkdownProp sets upper-cased property in a list keeping order
+// of existing properties.
+func setMarkdownProp(props [][2]string, name, value string) [][2]string {
+	name = strings.ToUpper(name)
+	for idx := range props {
+		if props[idx][0] == name {
+			props[idx][1] = value
+			return props
+		}
+	}
+
+	return append(props, [2]string{name, value})
+}
+
+// markdownInline converts inline Markdown markup of a line to org.
+func markdownInline(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range mdInlineRegexp.FindAllStringSubmatchIndex(text, -1) {
+		sb.WriteString(markdownEmphasis(text[last:m[0]]))
+		switch {
+		case m[2] >= 0:
+			sb.WriteString("~" + text[m[2]:m[3]] + "~")
+		case m[6] >= 0:
+			sb.WriteString("[[" + text[m[6]:m[7]] + "]]")
+		default:
+			sb.WriteString("[[" + text[m[10]:m[11]] + "][" + markdownEmphasis(text[m[8]:m[9]]) + "]]")
+		}
+		last = m[1]
+	}
+	sb.WriteString(markdownEmphasis(text[last:]))
+
+	return sb.String()
+}
+
+// markdownEmphasis converts bold, italic and strike-through markup to
+// org. Underscores inside words are left as is.
+func markdownEmphasis(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range mdEmphasisRegexp.FindAllStringSubmatchIndex(text, -1) {
+		if (m[4] >= 0 || m[8] >= 0) && (isWordBefore(text, m[0]) || isWordAfter(text, m[1])) {
+			continue
+		}
+
+		sb.WriteString(text[last:m[0]])
+		switch {
+		case m[2] >= 0:
+			sb.WriteString("*" + text[m[2]:m[3]] + "*")
+		case m[4] >= 0:
+			sb.WriteString("*" + text[m[4]:m[5]] + "*")
+		case m[6] >= 0:
+			sb.WriteString("/" + text[m[6]:m[7]] + "/")
+		case m[8] >= 0:
+			sb.WriteString("/" + text[m[8]:m[9]] + "/")
+		default:
+			sb.WriteString("+" + text[m[10]:m[11]] + "+")
+		}
+		last = m[1]
+	}
+	sb.WriteString(text[last:])
+
+	return sb.String()
+}
+
+func isWordBefore(text string, idx int) bool {
+	r, _ := utf8.DecodeLastRuneInString(text[:idx])
+	return idx > 0 && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func isWordAfter(text string, idx int) bool {
+	r, _ := utf8.DecodeRuneInString(text[idx:])
+	return idx < len(text) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// markdownHeadlineLines returns line numbers of Markdown headings,
+// fenced blocks and front matter are skipped.
+func markdownHeadlineLines(lines []string) []int {
+	_, idx := markdownFrontMatter(lines)
+
+	result := make([]int, 0)
+	for idx < len(lines) {
+		if m := mdFenceRegexp.FindStringSubmatch(lines[idx]); m != nil {
+			idx = markdownFenceEnd(lines, idx, m[1]) + 1
+			continue
+		}
+
+		if mdHeadingRegexp.MatchString(lines[idx]) {
+			result = append(result, idx)
+		}
+		idx++
+	}
+
+	return result
+}
+
+// setMarkdownProperty sets property of a Markdown heading at a given
+// line. Existing property comments and lines of "properties" fenced
+// blocks are updated in place, missing properties are added as a
+// comment after existing ones.
+func setMarkdownProperty(lines []string, headline int, name, value string) []string {
+	idx := headline + 1
+	for idx < len(lines) {
+		if m := mdPropertyRegexp.FindStringSubmatch(lines[idx]); m != nil {
+			if strings.EqualFold(m[1], name) {
+				lines[idx] = "<!-- " + m[1] + ": " + value + " -->"
+				return lines
+			}
+			idx++
+			continue
+		}
+
+		if m := mdFenceRegexp.FindStringSubmatch(lines[idx]); m != nil && m[2] == "properties" {
+			end := markdownFenceEnd(lines, idx, m[1])
+			for line := idx + 1; line < end; line++ {
+				if kv := mdKeyValueRegexp.FindStringSubmatch(lines[line]); kv != nil && strings.EqualFold(kv[1], name) {
+					lines[line] = kv[1] + ": " + value
+					return lines
+				}
+			}
+			idx = end + 1
+			continue
+		}
+
+		break
+	}
+
+	if idx > len(lines) {
+		idx = len(lines)
+	}
+
+	result := make([]string, 0, len(lines)+1)
+	result = append(result, lines[:idx]...)
+	result = append(result, "<!-- "+name+": "+value+" -->")
+	return append(result, lines[idx:]...)
+}
@@ -0,0 +1,86 @@
+package leaf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownToOrg(t *testing.T) {
+	md := strings.Join([]string{
+		"---",
+		"rater: self",
+		"per_review: '10'",
+		"---",
+		"# Deck",
+		"<!-- ALGORITHM: sm2 -->",
+		"## **bold** and _em_ in snake_case",
+		"<!-- ID: 1 -->",
+		"* side 1",
+		"+ side 2",
+		"## `code` [link](http://example.com/a_b) ![img](images/a.png)",
+		"> quote",
+		"",
+		"---",
+		"```go",
+		"# not a heading",
+		"```",
+		"# Other deck",
+		"```properties",
+		"RATER: auto",
+		"```",
+		"## ~~strike~~ ##",
+		"answer",
+	}, "\n")
+
+	assert.Equal(t, strings.Join([]string{
+		"* Deck",
+		":PROPERTIES:",
+		":RATER: self",
+		":PER_REVIEW: 10",
+		":ALGORITHM: sm2",
+		":END:",
+		"** *bold* and /em/ in snake_case",
+		":PROPERTIES:",
+		":ID: 1",
+		":END:",
+		"- side 1",
+		"- side 2",
+		"** ~code~ [[http://example.com/a_b][link]] [[images/a.png]]",
+		"#+BEGIN_QUOTE",
+		"quote",
+		"#+END_QUOTE",
+		"",
+		"-----",
+		"#+BEGIN_SRC go",
+		"# not a heading",
+		"#+END_SRC",
+		"* Other deck",
+		":PROPERTIES:",
+		":RATER: auto",
+		":PER_REVIEW: 10",
+		":END:",
+		"** +strike+",
+		"answer",
+	}, "\n"), markdownToOrg(md))
+}
+
+func TestMarkdownHeadlineLines(t *testing.T) {
+	lines := strings.Split("---\ntitle: foo\n---\n# Deck\n## foo\n```\n# bar\n```\n## baz\n", "\n")
+	assert.Equal(t, []int{3, 4, 8}, markdownHeadlineLines(lines))
+}
+
+func TestSetMarkdownProperty(t *testing.T) {
+	lines := strings.Split("# Deck\n<!-- RATER: self -->\n```properties\nALGORITHM: sm2\n```\n## foo\nbar", "\n")
+
+	lines = setMarkdownProperty(lines, 0, "RATER", "auto")
+	lines = setMarkdownProperty(lines, 0, "ALGORITHM", "ebisu")
+	lines = setMarkdownProperty(lines, 0, "PER_REVIEW", "10")
+	lines = setMarkdownProperty(lines, 6, "ID", "1")
+	assert.Equal(
+		t,
+		"# Deck\n<!-- RATER: auto -->\n```properties\nALGORITHM: ebisu\n```\n<!-- PER_REVIEW: 10 -->\n## foo\n<!-- ID: 1 -->\nbar",
+		strings.Join(lines, "\n"),
+	)
+}
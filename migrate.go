@@ -0,0 +1,199 @@
+package leaf
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// reviewState is an algorithm independent view of a card review
+// progress. Intervals are stored in days and difficulty is
+// normalised to [0, 1] range where 0 is the easiest.
+type reviewState struct {
+	lastReviewedAt time.Time
+	interval       float64
+	difficulty     float64
+	reviews        int
+	lapses         int
+	historical     []IntervalSnapshot
+}
+
+// MigrateStats converts stats produced by one algorithm into a
+// reasonable starting state for a different algorithm. Conversion
+// uses LastReviewedAt, Interval and Historical snapshots of the
+// source algorithm, cards that were never reviewed are reset to
+// defaults of the target algorithm. Converted stats use provided clock
+// and parameters.
+func MigrateStats(stats *Stats, to SRS, clock Clock, params SRSParams) (*Stats, error) {
+	state, err := extractReviewState(stats.SRSAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := NewStats(to, clock, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.reviews == 0 {
+		return result, nil
+	}
+
+	switch sm := result.SRSAlgorithm.(type) {
+	case *Supermemo2:
+		sm.LastReviewedAt = state.lastReviewedAt
+		sm.Interval = state.interval
+		sm.Easiness = 2.5 - 1.2*state.difficulty
+		sm.Total = state.reviews
+		sm.Correct = state.streak()
+		sm.Historical = state.historical
+	case *Supermemo2Plus:
+		sm.LastReviewedAt = state.lastReviewedAt
+		sm.Interval = math.Max(state.interval, 0.2)
+		sm.Difficulty = state.difficulty
+		sm.Historical = state.historical
+	case *Supermemo2PlusCustom:
+		sm.LastReviewedAt = state.lastReviewedAt
+		sm.Interval = math.Max(state.interval, sm.params.MinInterval)
+		sm.Difficulty = state.difficulty
+		sm.Historical = state.historical
+	case *Ebisu:
+		sm.LastReviewedAt = state.lastReviewedAt
+		sm.Interval = math.Max(state.interval, 0.2) * 24
+		sm.Historical = scaleSnapshots(state.historical, 24)
+	case *FSRS:
+		sm.LastReviewedAt = state.lastReviewedAt
+		sm.Interval = state.interval
+		sm.Stability = math.Max(state.interval, 0.1)
+		sm.Difficulty = 1 + 9*state.difficulty
+		sm.Reps = state.reviews
+		sm.Lapses = state.lapses
+		sm.Historical = state.historical
+	default:
+		return nil, fmt.Errorf("migrate: unsupported target algorithm %s", to)
+	}
+
+	return result, nil
+}
+
+// lastReviewedAt returns last review time of stats, next review
+// time is used for algorithms that don't expose it.
+func lastReviewedAt(s *Stats) time.Time {
+	state, err := extractReviewState(s.SRSAlgorithm)
+	if err != nil {
+		return s.NextReviewAt()
+	}
+
+	return state.lastReviewedAt
+}
+
+func extractReviewState(algo SRSAlgorithm) (*reviewState, error) {
+	var state *reviewState
+	switch sm := algo.(type) {
+	case *Supermemo2:
+		state = &reviewState{
+			lastReviewedAt: sm.LastReviewedAt,
+			interval:       sm.Interval,
+			difficulty:     (2.5 - sm.Easiness) / 1.2,
+			reviews:        sm.Total,
+			historical:     sm.Historical,
+		}
+	case *Supermemo2Plus:
+		state = &reviewState{
+			lastReviewedAt: sm.LastReviewedAt,
+			interval:       sm.Interval,
+			difficulty:     sm.Difficulty,
+			reviews:        len(sm.Historical),
+			historical:     sm.Historical,
+		}
+	case *Supermemo2PlusCustom:
+		state = &reviewState{
+			lastReviewedAt: sm.LastReviewedAt,
+			interval:       sm.Interval,
+			difficulty:     sm.Difficulty,
+			reviews:        len(sm.Historical),
+			historical:     sm.Historical,
+		}
+	case *Ebisu:
+		state = &reviewState{
+			lastReviewedAt: sm.LastReviewedAt,
+			interval:       sm.Interval / 24,
+			difficulty:     0.3,
+			reviews:        len(sm.Historical),
+			historical:     scaleSnapshots(sm.Historical, 1.0/24),
+		}
+	case *FSRS:
+		state = &reviewState{
+			lastReviewedAt: sm.LastReviewedAt,
+			interval:       sm.Interval,
+			difficulty:     (sm.Difficulty - 1) / 9,
+			reviews:        sm.Reps,
+			lapses:         sm.Lapses,
+			historical:     sm.Historical,
+		}
+	default:
+		return nil, fmt.Errorf("migrate: unsupported source algorithm %T", algo)
+	}
+
+	state.difficulty = math.Max(0, math.Min(1, state.difficulty))
+	if state.lapses == 0 {
+		state.lapses = state.countLapses()
+	}
+
+	return state, nil
+}
+
+// intervals returns historical intervals followed by the current one.
+func (state *reviewState) intervals() []float64 {
+	intervals := make([]float64, 0, len(state.historical)+1)
+	for _, snap := range state.historical {
+		intervals = append(intervals, snap.Interval)
+	}
+
+	return append(intervals, state.interval)
+}
+
+// countLapses approximates amount of failed reviews by counting
+// interval drops.
+func (state *reviewState) countLapses() int {
+	lapses := 0
+	intervals := state.intervals()
+	for i := 1; i < len(intervals); i++ {
+		if intervals[i] < intervals[i-1] {
+			lapses++
+		}
+	}
+
+	return lapses
+}
+
+// streak approximates amount of successful reviews since the last lapse.
+func (state *reviewState) streak() int {
+	streak := 0
+	intervals := state.intervals()
+	for i := len(intervals) - 1; i > 0; i-- {
+		if intervals[i] < intervals[i-1] {
+			break
+		}
+		streak++
+	}
+
+	if streak > state.reviews {
+		return state.reviews
+	}
+
+	return streak
+}
+
+func scaleSnapshots(snapshots []IntervalSnapshot, scale float64) []IntervalSnapshot {
+	if snapshots == nil {
+		return nil
+	}
+
+	result := make([]IntervalSnapshot, len(snapshots))
+	for idx, snap := range snapshots {
+		result[idx] = IntervalSnapshot{snap.Timestamp, snap.Interval * scale, snap.Factor}
+	}
+
+	return result
+}
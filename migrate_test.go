@@ -0,0 +1,94 @@
+package leaf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateStats(t *testing.T) {
+	clock := NewSimulatedClock(time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC))
+	reviewedAt := clock.Now().Add(-24 * time.Hour)
+	sm2 := &Supermemo2{
+		LastReviewedAt: reviewedAt,
+		Interval:       6,
+		Easiness:       2.2,
+		Correct:        2,
+		Total:          3,
+		Historical:     []IntervalSnapshot{{0, 0, 2.5}, {0, 1, 2.5}, {0, 6, 2.2}},
+	}
+
+	t.Run("sm2 to fsrs", func(t *testing.T) {
+		s, err := MigrateStats(&Stats{sm2}, SRSFSRS, clock, nil)
+		require.NoError(t, err)
+
+		fs := s.SRSAlgorithm.(*FSRS)
+		assert.Equal(t, reviewedAt, fs.LastReviewedAt)
+		assert.InDelta(t, 6, fs.Interval, 0.01)
+		assert.InDelta(t, 6, fs.Stability, 0.01)
+		assert.InDelta(t, 3.25, fs.Difficulty, 0.01)
+		assert.Equal(t, 3, fs.Reps)
+		assert.Equal(t, 0, fs.Lapses)
+		assert.Len(t, fs.Historical, 3)
+	})
+
+	t.Run("sm2 to ebisu", func(t *testing.T) {
+		s, err := MigrateStats(&Stats{sm2}, SRSEbisu, clock, nil)
+		require.NoError(t, err)
+
+		eb := s.SRSAlgorithm.(*Ebisu)
+		assert.InDelta(t, 144, eb.Interval, 0.01)
+		assert.InDelta(t, 3, eb.Alpha, 0.01)
+		assert.Equal(t, sm2.NextReviewAt(), s.NextReviewAt())
+		assert.InDelta(t, 24, eb.Historical[1].Interval, 0.01)
+	})
+
+	t.Run("ebisu to sm2+c", func(t *testing.T) {
+		eb := &Ebisu{
+			LastReviewedAt: reviewedAt,
+			Alpha:          3,
+			Beta:           3,
+			Interval:       72,
+			Historical:     []IntervalSnapshot{{0, 24, 0}, {0, 48, 0}, {0, 24, 0}},
+		}
+		s, err := MigrateStats(&Stats{eb}, SRSSupermemo2PlusCustom, clock, nil)
+		require.NoError(t, err)
+
+		sm := s.SRSAlgorithm.(*Supermemo2PlusCustom)
+		assert.InDelta(t, 3, sm.Interval, 0.01)
+		assert.InDelta(t, 0.3, sm.Difficulty, 0.01)
+		assert.InDelta(t, 2, sm.Historical[1].Interval, 0.01)
+	})
+
+	t.Run("fsrs to sm2", func(t *testing.T) {
+		fs := &FSRS{
+			LastReviewedAt: reviewedAt,
+			Stability:      10,
+			Difficulty:     10,
+			Interval:       10,
+			Reps:           4,
+			Lapses:         1,
+			Historical:     []IntervalSnapshot{{0, 0, 5}, {0, 5, 6}, {0, 1, 7}, {0, 4, 8}},
+		}
+		s, err := MigrateStats(&Stats{fs}, SRSSupermemo2, clock, nil)
+		require.NoError(t, err)
+
+		sm := s.SRSAlgorithm.(*Supermemo2)
+		assert.InDelta(t, 10, sm.Interval, 0.01)
+		assert.InDelta(t, 1.3, sm.Easiness, 0.01)
+		assert.Equal(t, 4, sm.Total)
+		assert.Equal(t, 2, sm.Correct)
+	})
+
+	t.Run("new card", func(t *testing.T) {
+		s, err := MigrateStats(&Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}, SRSSupermemo2, clock, nil)
+		require.NoError(t, err)
+
+		sm := s.SRSAlgorithm.(*Supermemo2)
+		assert.InDelta(t, 0, sm.Interval, 0.01)
+		assert.InDelta(t, 2.5, sm.Easiness, 0.01)
+		assert.Equal(t, clock.Now(), sm.NextReviewAt())
+	})
+}
@@ -0,0 +1,193 @@
+package leaf
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// optimizeSteps is an amount of evaluated values within a parameter range.
+const optimizeSteps = 11
+
+// optimizePasses is a maximum amount of coordinate search passes.
+const optimizePasses = 3
+
+// paramRange defines searched range of a single algorithm parameter.
+type paramRange struct {
+	name     string
+	min, max float64
+}
+
+// optimizeSpace defines tunable parameters of built-in algorithms.
+// Ranges are narrower than accepted values to keep search reasonable.
+var optimizeSpace = map[SRS][]paramRange{
+	SRSSupermemo2: {
+		{"INITIAL_EASE", 1.3, 3.3},
+		{"MIN_EASE", 1.1, 2.1},
+	},
+	SRSSupermemo2Plus: {
+		{"INITIAL_DIFFICULTY", 0, 1},
+		{"DIFFICULTY_RATE", 5, 55},
+		{"DIFFICULTY_WEIGHT", minDifficultyWeight, 7},
+	},
+	SRSSupermemo2PlusCustom: {
+		{"INITIAL_DIFFICULTY", 0, 1},
+		{"DIFFICULTY_RATE", 5, 55},
+		{"DIFFICULTY_WEIGHT", minDifficultyWeight, 7},
+		{"MIN_INTERVAL", 0, 0.5},
+	},
+	SRSEbisu: {
+		{"EBISU_ALPHA", 2, 12},
+		{"EBISU_BETA", 2, 12},
+		{"EBISU_HALFLIFE", 12, 132},
+	},
+}
+
+// OptimizeResult describes parameters found for a review log.
+type OptimizeResult struct {
+	Algorithm   SRS       `json:"algorithm"`
+	Params      SRSParams `json:"params"`
+	Loss        float64   `json:"loss"`
+	InitialLoss float64   `json:"initial_loss"`
+	Reviews     int       `json:"reviews"`
+}
+
+// OptimizeParams searches parameters of a given algorithm that
+// minimise log-loss of predicted recall against outcomes of recorded
+// reviews. Review log is replayed for each candidate similar to
+// ReplayReviews, recall of a card is predicted right before each
+// review apart from the first one. Search starts from provided
+// parameters, returned parameters contain only tuned values,
+// parameters left at algorithm defaults are omitted.
+func OptimizeParams(reviews []Review, srs SRS, params SRSParams) (*OptimizeResult, error) {
+	space, ok := optimizeSpace[srs]
+	if !ok {
+		return nil, fmt.Errorf("optimize: algorithm %q has no tunable parameters", srs)
+	}
+
+	sorted := make([]Review, 0, len(reviews))
+	for _, review := range reviews {
+		if review.Score != ReviewScoreAgain {
+			sorted = append(sorted, review)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ReviewedAt.Before(sorted[j].ReviewedAt)
+	})
+
+	best := make(SRSParams, len(params))
+	for k, v := range params {
+		best[k] = v
+	}
+
+	initialLoss, total, err := recallLoss(sorted, srs, best)
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("optimize: not enough reviews")
+	}
+
+	bestLoss := initialLoss
+	for pass := 0; pass < optimizePasses; pass++ {
+		improved := false
+		for _, r := range space {
+			for step := 0; step < optimizeSteps; step++ {
+				value := r.min + (r.max-r.min)*float64(step)/(optimizeSteps-1)
+				value = math.Round(value*100) / 100
+				candidate := make(SRSParams, len(best))
+				for k, v := range best {
+					candidate[k] = v
+				}
+				candidate[r.name] = strconv.FormatFloat(value, 'f', -1, 64)
+
+				loss, _, err := recallLoss(sorted, srs, candidate)
+				if err != nil {
+					continue
+				}
+
+				if loss < bestLoss-1e-9 {
+					best, bestLoss, improved = candidate, loss, true
+				}
+			}
+		}
+
+		if !improved {
+			break
+		}
+	}
+
+	result := &OptimizeResult{srs, make(SRSParams, len(space)), bestLoss, initialLoss, total}
+	for _, r := range space {
+		value, err := best.Float(r.name, math.NaN())
+		if err != nil {
+			return nil, err
+		}
+
+		if math.IsNaN(value) {
+			continue
+		}
+		result.Params[r.name] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	return result, nil
+}
+
+// recallLoss replays sorted reviews using provided parameters and
+// returns mean log-loss of predicted recall along with amount of
+// scored reviews.
+func recallLoss(reviews []Review, srs SRS, params SRSParams) (float64, int, error) {
+	clock := NewSimulatedClock(time.Time{})
+	replayed := make(map[string]*Stats)
+
+	loss, total := 0.0, 0
+	for _, review := range reviews {
+		clock.Set(review.ReviewedAt)
+		stats := replayed[review.Card]
+		if stats == nil {
+			var err error
+			if stats, err = NewStats(srs, clock, params); err != nil {
+				return 0, 0, err
+			}
+			replayed[review.Card] = stats
+		} else {
+			p := math.Max(1e-4, math.Min(1-1e-4, predictedRecall(stats.SRSAlgorithm, clock.Now())))
+			if review.Rating >= ratingSuccess {
+				loss -= math.Log(p)
+			} else {
+				loss -= math.Log(1 - p)
+			}
+			total++
+		}
+
+		stats.Advance(review.Rating)
+	}
+
+	if total == 0 {
+		return 0, 0, nil
+	}
+
+	return loss / float64(total), total, nil
+}
+
+// predictedRecall returns probability of recall of a card at a given
+// time. Algorithms without own recall model are assumed to schedule
+// reviews at 90% recall and forget exponentially.
+func predictedRecall(algo SRSAlgorithm, now time.Time) float64 {
+	switch sm := algo.(type) {
+	case *Ebisu:
+		return sm.predictRecall()
+	case *FSRS:
+		return sm.Retrievability()
+	}
+
+	state, err := extractReviewState(algo)
+	if err != nil || state.interval <= 0 {
+		return 0
+	}
+
+	elapsed := float64(now.Sub(state.lastReviewedAt)) / float64(24*time.Hour)
+	return ExponentialCurve(math.Max(0, elapsed), state.interval)
+}
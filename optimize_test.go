@@ -0,0 +1,100 @@
+package leaf
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptimizeParams(t *testing.T) {
+	start := time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+	rnd := rand.New(rand.NewSource(1))
+	reviews := make([]Review, 0)
+	for card := 0; card < 30; card++ {
+		reviewedAt := start.Add(time.Duration(card) * time.Hour)
+		reviews = append(reviews, Review{Card: fmt.Sprint(card), ReviewedAt: reviewedAt, Score: ReviewScoreGood, Rating: 1})
+
+		stability := 2.0
+		for _, days := range []float64{1, 3, 7, 15, 30} {
+			reviewedAt = reviewedAt.Add(time.Duration(days * 24 * float64(time.Hour)))
+			review := Review{Card: fmt.Sprint(card), ReviewedAt: reviewedAt, Score: ReviewScoreGood, Rating: 1}
+			if rnd.Float64() < ExponentialCurve(days, stability) {
+				stability *= 2.5
+			} else {
+				review.Rating = 0.59
+				stability *= 0.5
+			}
+			reviews = append(reviews, review)
+		}
+	}
+	reviews = append(reviews, Review{Card: "0", ReviewedAt: start, Score: ReviewScoreAgain})
+
+	for _, srs := range []SRS{SRSSupermemo2, SRSSupermemo2Plus, SRSSupermemo2PlusCustom, SRSEbisu} {
+		t.Run(string(srs), func(t *testing.T) {
+			res, err := OptimizeParams(reviews, srs, nil)
+			require.NoError(t, err)
+			assert.Equal(t, srs, res.Algorithm)
+			assert.Equal(t, 150, res.Reviews)
+			assert.True(t, res.Loss > 0)
+			assert.True(t, res.Loss < res.InitialLoss)
+			assert.NotEmpty(t, res.Params)
+
+			for name := range res.Params {
+				assert.Contains(t, []string{
+					"INITIAL_EASE", "MIN_EASE", "INITIAL_DIFFICULTY", "DIFFICULTY_RATE",
+					"DIFFICULTY_WEIGHT", "MIN_INTERVAL", "EBISU_ALPHA", "EBISU_BETA", "EBISU_HALFLIFE",
+				}, name)
+			}
+
+			_, err = NewStats(srs, SystemClock, res.Params)
+			require.NoError(t, err)
+
+			again, err := OptimizeParams(reviews, srs, res.Params)
+			require.NoError(t, err)
+			assert.InDelta(t, res.Loss, again.InitialLoss, 1e-9)
+			assert.InDelta(t, res.Loss, again.Loss, 1e-9)
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := OptimizeParams(reviews, SRSFSRS, nil)
+		assert.EqualError(t, err, `optimize: algorithm "fsrs" has no tunable parameters`)
+	})
+
+	t.Run("not enough reviews", func(t *testing.T) {
+		_, err := OptimizeParams(reviews[:1], SRSSupermemo2, nil)
+		assert.EqualError(t, err, "optimize: not enough reviews")
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		_, err := OptimizeParams(reviews, SRSSupermemo2, SRSParams{"MIN_EASE": "0"})
+		assert.Error(t, err)
+	})
+}
+
+func TestOptimizeSpace(t *testing.T) {
+	for srs, ranges := range optimizeSpace {
+		for _, r := range ranges {
+			for _, value := range []float64{r.min, r.max} {
+				_, err := NewStats(srs, SystemClock, SRSParams{r.name: fmt.Sprint(value)})
+				assert.NoError(t, err, "%s %s", srs, r.name)
+			}
+		}
+	}
+}
+
+func TestPredictedRecall(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm := &Supermemo2{LastReviewedAt: clock.Now().Add(-24 * time.Hour), Interval: 1}
+	assert.InDelta(t, 0.9, predictedRecall(sm, clock.Now()), 0.01)
+	assert.InDelta(t, 0.81, predictedRecall(sm, clock.Now().Add(24*time.Hour)), 0.01)
+
+	eb := &Ebisu{LastReviewedAt: clock.Now().Add(-1 * time.Hour), Alpha: 4, Beta: 4, Interval: 24, clock: clock}
+	assert.InDelta(t, 0.96, predictedRecall(eb, clock.Now()), 0.01)
+
+	assert.Equal(t, 0.0, predictedRecall(NewSupermemo2(clock, DefaultSupermemo2Params()), clock.Now()))
+}
@@ -0,0 +1,55 @@
+package leaf
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SRSParams contains algorithm parameters defined in a deck property
+// drawer. Keys are upper cased property names, algorithms ignore
+// parameters they don't support.
+type SRSParams map[string]string
+
+// Float returns value of a float parameter. Provided default value
+// is returned for missing parameters.
+func (p SRSParams) Float(name string, value float64) (float64, error) {
+	raw, ok := p[name]
+	if !ok {
+		return value, nil
+	}
+
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("params: invalid %s value %q", name, raw)
+	}
+
+	return v, nil
+}
+
+// paramsParser reads float parameters and keeps the first error.
+type paramsParser struct {
+	params SRSParams
+	err    error
+}
+
+// float reads a parameter into a given value, value is left intact
+// for missing parameters. Parsed values should be within [min, max]
+// range.
+func (p *paramsParser) float(name string, value *float64, min, max float64) {
+	if p.err != nil {
+		return
+	}
+
+	v, err := p.params.Float(name, *value)
+	if err != nil {
+		p.err = err
+		return
+	}
+
+	if v < min || v > max {
+		p.err = fmt.Errorf("params: %s should be within [%g, %g] range, got %g", name, min, max, v)
+		return
+	}
+
+	*value = v
+}
@@ -0,0 +1,64 @@
+package leaf
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSRSParams(t *testing.T) {
+	params := SRSParams{"MAX_INTERVAL": "30", "INITIAL_EASE": "foo"}
+
+	v, err := params.Float("MAX_INTERVAL", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 30.0, v)
+
+	v, err = params.Float("MIN_INTERVAL", 0.2)
+	require.NoError(t, err)
+	assert.Equal(t, 0.2, v)
+
+	_, err = params.Float("INITIAL_EASE", 2.5)
+	assert.EqualError(t, err, `params: invalid INITIAL_EASE value "foo"`)
+
+	t.Run("algorithms", func(t *testing.T) {
+		sm2, err := ParseSupermemo2Params(SRSParams{"INITIAL_EASE": "2", "MAX_INTERVAL": "30"})
+		require.NoError(t, err)
+		assert.Equal(t, Supermemo2Params{2, 1.3, 30}, sm2)
+
+		sm2, err = ParseSupermemo2Params(nil)
+		require.NoError(t, err)
+		assert.Equal(t, math.Inf(1), sm2.MaxInterval)
+
+		_, err = ParseSupermemo2Params(SRSParams{"INITIAL_EASE": "1.5", "MIN_EASE": "2"})
+		assert.Error(t, err)
+
+		sm2p, err := ParseSupermemo2PlusParams(SRSParams{"DIFFICULTY_RATE": "20"}, DefaultSupermemo2PlusCustomParams())
+		require.NoError(t, err)
+		assert.Equal(t, Supermemo2PlusParams{0.3, 20, 3.5, 0.2, 300}, sm2p)
+
+		_, err = ParseSupermemo2PlusParams(SRSParams{"INITIAL_DIFFICULTY": "2"}, DefaultSupermemo2PlusParams())
+		assert.EqualError(t, err, "params: INITIAL_DIFFICULTY should be within [0, 1] range, got 2")
+
+		eb, err := ParseEbisuParams(SRSParams{"EBISU_HALFLIFE": "48"})
+		require.NoError(t, err)
+		assert.Equal(t, EbisuParams{3, 3, 48}, eb)
+
+		fs, err := ParseFSRSParams(SRSParams{"DESIRED_RETENTION": "0.8"})
+		require.NoError(t, err)
+		assert.Equal(t, FSRSParams{0.8, 36500}, fs)
+
+		_, err = ParseFSRSParams(SRSParams{"DESIRED_RETENTION": "1"})
+		assert.Error(t, err)
+	})
+
+	t.Run("NewStats", func(t *testing.T) {
+		s, err := NewStats(SRSSupermemo2PlusCustom, SystemClock, SRSParams{"MAX_INTERVAL": "30"})
+		require.NoError(t, err)
+		assert.Equal(t, 30.0, s.SRSAlgorithm.(*Supermemo2PlusCustom).params.MaxInterval)
+
+		_, err = NewStats(SRSEbisu, SystemClock, SRSParams{"EBISU_ALPHA": "0"})
+		assert.Error(t, err)
+	})
+}
@@ -35,15 +35,45 @@ type Rater interface {
 	Rate(question string, score ReviewScore) float64
 }
 
+// SidesRater rates review attempt of a card with multiple sides
+// based on correctness of each side. Rating should be within [0, 1]
+// range.
+type SidesRater interface {
+	RateSides(question string, correct []bool) float64
+}
+
+// SidesScore returns score of a review attempt with provided
+// correctness of each side: "easy" if all sides are correct, "again"
+// if none of them are and "hard" otherwise.
+func SidesScore(correct []bool) ReviewScore {
+	missed := 0
+	for _, c := range correct {
+		if !c {
+			missed++
+		}
+	}
+
+	switch {
+	case missed == 0 && len(correct) > 0:
+		return ReviewScoreEasy
+	case missed == len(correct):
+		return ReviewScoreAgain
+	default:
+		return ReviewScoreHard
+	}
+}
+
 type harshRater struct {
-	mistakes map[string]int
+	mistakes map[string]float64
 }
 
 // HarshRater returns miss count based Rater. Miss counter will
 // increase for each "again" score. Rating declines really fast and
-// even 1 mistake results in 0.59 rating.
+// even 1 mistake results in 0.59 rating. Returned Rater also
+// implements SidesRater, partially correct attempts increase miss
+// counter by a share of missed sides.
 func HarshRater() Rater {
-	return &harshRater{make(map[string]int)}
+	return &harshRater{make(map[string]float64)}
 }
 
 func (rater harshRater) Rate(question string, score ReviewScore) float64 {
@@ -57,7 +87,22 @@ func (rater harshRater) Rate(question string, score ReviewScore) float64 {
 		return 1
 	}
 
-	return math.Max(0, 0.79-float64(mistakes)/5)
+	return math.Max(0, 0.79-mistakes/5)
+}
+
+func (rater harshRater) RateSides(question string, correct []bool) float64 {
+	score := SidesScore(correct)
+	if score == ReviewScoreHard {
+		missed := 0
+		for _, c := range correct {
+			if !c {
+				missed++
+			}
+		}
+		rater.mistakes[question] += float64(missed) / float64(len(correct))
+	}
+
+	return rater.Rate(question, score)
 }
 
 type tableRater struct {
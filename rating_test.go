@@ -21,6 +21,22 @@ func TestHarshRater(t *testing.T) {
 	}
 }
 
+func TestHarshRaterSides(t *testing.T) {
+	rater := HarshRater().(SidesRater)
+
+	assert.InDelta(t, 1.0, rater.RateSides("foo", []bool{true, true}), 0.01)
+	assert.InDelta(t, 0.69, rater.RateSides("foo", []bool{true, false}), 0.01)
+	assert.InDelta(t, 0, rater.RateSides("foo", []bool{false, false}), 0.01)
+	assert.InDelta(t, 0.49, rater.RateSides("foo", []bool{true, true}), 0.01)
+}
+
+func TestSidesScore(t *testing.T) {
+	assert.Equal(t, ReviewScoreEasy, SidesScore([]bool{true, true}))
+	assert.Equal(t, ReviewScoreHard, SidesScore([]bool{false, true}))
+	assert.Equal(t, ReviewScoreAgain, SidesScore([]bool{false, false}))
+	assert.Equal(t, ReviewScoreAgain, SidesScore(nil))
+}
+
 func TestTableRater(t *testing.T) {
 	rater := TableRater()
 
@@ -0,0 +1,119 @@
+package leaf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// SRSFactory constructs a new algorithm state with default values.
+// Algorithm should use provided clock for scheduling and deck
+// parameters to adjust it's curve. Error should be returned for
+// invalid parameters.
+type SRSFactory func(clock Clock, params SRSParams) (SRSAlgorithm, error)
+
+var (
+	registryMu sync.RWMutex
+	factories  = make(map[SRS]SRSFactory)
+	parameters = make(map[SRS][]string)
+	algorithms = make(map[reflect.Type]SRS)
+)
+
+func init() {
+	supermemo2PlusParams := []string{"INITIAL_DIFFICULTY", "DIFFICULTY_RATE", "DIFFICULTY_WEIGHT", "MIN_INTERVAL", "MAX_INTERVAL"}
+	RegisterSRS(SRSSupermemo2, func(clock Clock, params SRSParams) (SRSAlgorithm, error) {
+		p, err := ParseSupermemo2Params(params)
+		return NewSupermemo2(clock, p), err
+	}, "INITIAL_EASE", "MIN_EASE", "MAX_INTERVAL")
+	RegisterSRS(SRSSupermemo2Plus, func(clock Clock, params SRSParams) (SRSAlgorithm, error) {
+		p, err := ParseSupermemo2PlusParams(params, DefaultSupermemo2PlusParams())
+		return NewSupermemo2Plus(clock, p), err
+	}, supermemo2PlusParams...)
+	RegisterSRS(SRSSupermemo2PlusCustom, func(clock Clock, params SRSParams) (SRSAlgorithm, error) {
+		p, err := ParseSupermemo2PlusParams(params, DefaultSupermemo2PlusCustomParams())
+		return NewSupermemo2PlusCustom(clock, p), err
+	}, supermemo2PlusParams...)
+	RegisterSRS(SRSEbisu, func(clock Clock, params SRSParams) (SRSAlgorithm, error) {
+		p, err := ParseEbisuParams(params)
+		return NewEbisu(clock, p), err
+	}, "EBISU_ALPHA", "EBISU_BETA", "EBISU_HALFLIFE")
+	RegisterSRS(SRSFSRS, func(clock Clock, params SRSParams) (SRSAlgorithm, error) {
+		p, err := ParseFSRSParams(params)
+		return NewFSRS(clock, p), err
+	}, "DESIRED_RETENTION", "MAX_INTERVAL")
+}
+
+// RegisterSRS makes an algorithm available under a given name for
+// decks and stats. Each algorithm should be implemented by a distinct
+// type, since stats are tagged with an algorithm name using type of
+// the SRSAlgorithm. Params are names of deck properties read by the
+// factory, other deck properties are reported by the linter.
+// RegisterSRS is intended to be called from init functions and panics
+// if called twice for the same name or type.
+func RegisterSRS(name SRS, factory SRSFactory, params ...string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("leaf: RegisterSRS name is empty")
+	}
+	if factory == nil {
+		panic("leaf: RegisterSRS factory is nil for " + string(name))
+	}
+	if _, dup := factories[name]; dup {
+		panic("leaf: RegisterSRS called twice for " + string(name))
+	}
+
+	sm, err := factory(SystemClock, nil)
+	if err != nil {
+		panic(fmt.Sprintf("leaf: RegisterSRS default parameters are invalid for %s: %s", name, err))
+	}
+
+	algo := reflect.TypeOf(sm)
+	if existing, dup := algorithms[algo]; dup {
+		panic(fmt.Sprintf("leaf: RegisterSRS type %s is already registered for %s", algo, existing))
+	}
+
+	factories[name] = factory
+	parameters[name] = append([]string{}, params...)
+	algorithms[algo] = name
+}
+
+// AvailableSRS returns sorted names of all registered algorithms.
+func AvailableSRS() []SRS {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	result := make([]SRS, 0, len(factories))
+	for name := range factories {
+		result = append(result, name)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+
+	return result
+}
+
+func lookupSRS(name SRS) (SRSFactory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, ok := factories[name]
+	return factory, ok
+}
+
+// lookupSRSParams returns names of deck properties read by an
+// algorithm.
+func lookupSRSParams(name SRS) []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return parameters[name]
+}
+
+func algorithmName(algo SRSAlgorithm) SRS {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return algorithms[reflect.TypeOf(algo)]
+}
@@ -0,0 +1,121 @@
+package leaf
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedInterval struct {
+	LastReviewedAt time.Time
+	Reviews        int
+	clock          Clock
+}
+
+func (fi *fixedInterval) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		LastReviewedAt time.Time
+		Reviews        int
+	}{fi.LastReviewedAt, fi.Reviews})
+}
+
+func (fi *fixedInterval) UnmarshalJSON(b []byte) error {
+	payload := &struct {
+		LastReviewedAt time.Time
+		Reviews        int
+	}{}
+	if err := json.Unmarshal(b, payload); err != nil {
+		return err
+	}
+
+	fi.LastReviewedAt = payload.LastReviewedAt
+	fi.Reviews = payload.Reviews
+	return nil
+}
+
+func (fi *fixedInterval) Advance(rating float64) float64 {
+	fi.LastReviewedAt = timeNow(fi.clock)
+	fi.Reviews++
+	return 1
+}
+
+func (fi *fixedInterval) NextReviewAt() time.Time {
+	return fi.LastReviewedAt.Add(24 * time.Hour)
+}
+
+func (fi *fixedInterval) Less(other SRSAlgorithm) bool {
+	return fi.NextReviewAt().After(other.NextReviewAt())
+}
+
+func init() {
+	RegisterSRS("fixed", func(clock Clock, params SRSParams) (SRSAlgorithm, error) {
+		return &fixedInterval{LastReviewedAt: timeNow(clock).Add(-24 * time.Hour), clock: clock}, nil
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("AvailableSRS", func(t *testing.T) {
+		assert.Equal(t, []SRS{SRSEbisu, "fixed", SRSFSRS, SRSSupermemo2, SRSSupermemo2Plus, SRSSupermemo2PlusCustom}, AvailableSRS())
+	})
+
+	t.Run("params", func(t *testing.T) {
+		assert.Equal(t, []string{"INITIAL_EASE", "MIN_EASE", "MAX_INTERVAL"}, lookupSRSParams(SRSSupermemo2))
+		assert.Empty(t, lookupSRSParams("fixed"))
+		assert.Empty(t, lookupSRSParams("foo"))
+	})
+
+	t.Run("NewStats", func(t *testing.T) {
+		clock := NewSimulatedClock(time.Unix(100, 0))
+		s, err := NewStats("fixed", clock, nil)
+		require.NoError(t, err)
+		assert.Equal(t, SRS("fixed"), s.Algorithm())
+		assert.Equal(t, clock.Now(), s.NextReviewAt())
+
+		s, err = NewStats(SRSFSRS, clock, nil)
+		require.NoError(t, err)
+		assert.Equal(t, SRS(SRSFSRS), s.Algorithm())
+
+		_, err = NewStats("foo", clock, nil)
+		assert.EqualError(t, err, `srs: unknown algorithm "foo"`)
+
+		_, err = NewStats("", clock, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("StatsStore", func(t *testing.T) {
+		tmpfile, err := ioutil.TempFile("", "leaf.db")
+		require.NoError(t, err)
+		defer os.Remove(tmpfile.Name())
+
+		db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+		require.NoError(t, err)
+
+		s, err := NewStats("fixed", SystemClock, nil)
+		require.NoError(t, err)
+		s.Advance(1)
+		require.NoError(t, db.SaveStats("deck", "foo", s))
+
+		err = db.RangeStats("deck", "fixed", SystemClock, nil, func(card string, stats *Stats) bool {
+			fi := stats.SRSAlgorithm.(*fixedInterval)
+			assert.Equal(t, 1, fi.Reviews)
+			return true
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		assert.Panics(t, func() {
+			RegisterSRS("fixed", func(clock Clock, params SRSParams) (SRSAlgorithm, error) { return &fixedInterval{}, nil })
+		})
+		assert.Panics(t, func() {
+			RegisterSRS("fixed2", func(clock Clock, params SRSParams) (SRSAlgorithm, error) { return &fixedInterval{}, nil })
+		})
+		assert.Panics(t, func() { RegisterSRS("", nil) })
+		assert.Panics(t, func() { RegisterSRS("nil", nil) })
+	})
+}
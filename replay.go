@@ -0,0 +1,64 @@
+package leaf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ReplayReviews re-runs a review log through a given algorithm and
+// returns resulting stats for each reviewed card. Each review is
+// applied at its original time using a simulated clock, so results
+// are deterministic. Re-queued attempts ("again" score) don't
+// advance algorithms and are skipped, their effect is already
+// reflected in the rating of the final attempt. Provided reviews are
+// not modified, returned stats will use provided clock. Algorithm is
+// configured with provided parameters.
+func ReplayReviews(reviews []Review, srs SRS, clock Clock, params SRSParams) (map[string]*Stats, error) {
+	sorted := make([]Review, len(reviews))
+	copy(sorted, reviews)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ReviewedAt.Before(sorted[j].ReviewedAt)
+	})
+
+	replayClock := NewSimulatedClock(time.Time{})
+	replayed := make(map[string]*Stats)
+	for _, review := range sorted {
+		if review.Score == ReviewScoreAgain {
+			continue
+		}
+
+		replayClock.Set(review.ReviewedAt)
+		stats := replayed[review.Card]
+		if stats == nil {
+			var err error
+			if stats, err = NewStats(srs, replayClock, params); err != nil {
+				return nil, err
+			}
+			replayed[review.Card] = stats
+		}
+
+		stats.Advance(review.Rating)
+	}
+
+	result := make(map[string]*Stats, len(replayed))
+	for card, stats := range replayed {
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return nil, fmt.Errorf("json: %s", err)
+		}
+
+		s, err := NewStats(srs, clock, params)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(data, s); err != nil {
+			return nil, fmt.Errorf("json: %s", err)
+		}
+		result[card] = s
+	}
+
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package leaf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayReviews(t *testing.T) {
+	start := time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	reviews := []Review{
+		{Card: "foo", ReviewedAt: start.Add(7 * day), Score: ReviewScoreEasy, Rating: 1},
+		{Card: "foo", ReviewedAt: start, Score: ReviewScoreAgain, Rating: 0},
+		{Card: "foo", ReviewedAt: start, Score: ReviewScoreEasy, Rating: 0.59},
+		{Card: "foo", ReviewedAt: start.Add(day), Score: ReviewScoreEasy, Rating: 1},
+		{Card: "bar", ReviewedAt: start, Score: ReviewScoreGood, Rating: 0.6},
+	}
+
+	stats, err := ReplayReviews(reviews, SRSSupermemo2, SystemClock, nil)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+
+	clock := NewSimulatedClock(start)
+	expected := &Stats{NewSupermemo2(clock, DefaultSupermemo2Params())}
+	for _, review := range []Review{reviews[2], reviews[3], reviews[0]} {
+		clock.Set(review.ReviewedAt)
+		expected.Advance(review.Rating)
+	}
+
+	foo := stats["foo"].SRSAlgorithm.(*Supermemo2)
+	assert.Equal(t, 3, foo.Total)
+	assert.Equal(t, expected.SRSAlgorithm.(*Supermemo2).Interval, foo.Interval)
+	assert.Equal(t, expected.NextReviewAt(), foo.NextReviewAt())
+	assert.Equal(t, start.Add(7*day), foo.LastReviewedAt)
+	require.Len(t, foo.Historical, 3)
+	assert.Equal(t, start.Add(day).Unix(), foo.Historical[1].Timestamp)
+
+	bar := stats["bar"].SRSAlgorithm.(*Supermemo2)
+	assert.Equal(t, 1, bar.Total)
+	assert.Equal(t, start.Add(day), bar.NextReviewAt())
+
+	t.Run("deterministic", func(t *testing.T) {
+		again, err := ReplayReviews(reviews, SRSSupermemo2, SystemClock, nil)
+		require.NoError(t, err)
+		assert.Equal(t, stats, again)
+	})
+
+	t.Run("clock", func(t *testing.T) {
+		clock := NewSimulatedClock(start.Add(30 * day))
+		stats, err := ReplayReviews(reviews, SRSSupermemo2Plus, clock, nil)
+		require.NoError(t, err)
+
+		assert.True(t, stats["foo"].NextReviewAt().Before(clock.Now()))
+		sm := stats["foo"].SRSAlgorithm.(*Supermemo2Plus)
+		assert.InDelta(t, 2, sm.PercentOverdue(), 0.01)
+	})
+}
@@ -0,0 +1,28 @@
+package leaf
+
+import "time"
+
+// Review records a single review attempt of a card. Intervals are
+// stored in days.
+type Review struct {
+	Deck             string        `json:"deck"`
+	Card             string        `json:"card"`
+	SessionStartedAt time.Time     `json:"session_started_at"`
+	ReviewedAt       time.Time     `json:"reviewed_at"`
+	Score            ReviewScore   `json:"score"`
+	Rating           float64       `json:"rating"`
+	Answer           string        `json:"answer"`
+	Elapsed          time.Duration `json:"elapsed"`
+	PrevInterval     float64       `json:"prev_interval"`
+	Interval         float64       `json:"interval"`
+	Algorithm        SRS           `json:"algorithm"`
+}
+
+// ReviewLog defines storage interface that is used for storing raw
+// review attempts.
+type ReviewLog interface {
+	// LogReview appends a review attempt to the log.
+	LogReview(review *Review) error
+	// RangeReviews iterates over all reviews of a deck in chronological order.
+	RangeReviews(deck string, rangeFunc func(review *Review) bool) error
+}
@@ -2,31 +2,60 @@ package leaf
 
 import (
 	"errors"
+	"strings"
 	"time"
 )
 
 // StatsSaveFunc persists stats updates.
 type StatsSaveFunc func(card *CardWithStats) error
 
+// ReviewLogFunc persists review attempts.
+type ReviewLogFunc func(review *Review) error
+
 // ReviewSession contains parameters for a Deck review sessions.
 type ReviewSession struct {
 	statsSaver StatsSaveFunc
+	reviewLog  ReviewLogFunc
 	cards      []CardWithStats
-	queue      []string
+	// queue holds indexes of cards, questions of cards may repeat.
+	queue      []int
 	startedAt  time.Time
 	ratingType RatingType
+	clock      Clock
+
+	shownAt    time.Time
+	answeredAt time.Time
+	answer     string
 }
 
 // NewReviewSession constructs a new ReviewSession for a given set of cards.
 // Rating calculation will be performed using provided rater.
 // Provided StatsSaveFunc will be used for stats updates post review.
-func NewReviewSession(cards []CardWithStats, rt RatingType, statsSaver StatsSaveFunc) *ReviewSession {
-	queue := make([]string, len(cards))
-	for idx, card := range cards {
-		queue[idx] = card.Question
+// Review attempts are recorded using ReviewLogFunc if it's provided.
+// Review times are measured using provided clock.
+func NewReviewSession(
+	cards []CardWithStats,
+	rt RatingType,
+	statsSaver StatsSaveFunc,
+	reviewLog ReviewLogFunc,
+	clock Clock,
+) *ReviewSession {
+	queue := make([]int, len(cards))
+	for idx := range cards {
+		queue[idx] = idx
 	}
 
-	return &ReviewSession{statsSaver, cards, queue, time.Now(), rt}
+	now := timeNow(clock)
+	return &ReviewSession{
+		statsSaver: statsSaver,
+		reviewLog:  reviewLog,
+		cards:      cards,
+		queue:      queue,
+		startedAt:  now,
+		ratingType: rt,
+		clock:      clock,
+		shownAt:    now,
+	}
 }
 
 // StartedAt returns start time of the review session.
@@ -56,7 +85,17 @@ func (s *ReviewSession) Next() string {
 		return ""
 	}
 
-	return s.queue[0]
+	return s.cards[s.queue[0]].Question
+}
+
+// CardID returns ID of a current card to review.
+func (s *ReviewSession) CardID() string {
+	card := s.currentCard()
+	if card == nil {
+		return ""
+	}
+
+	return card.ID
 }
 
 // CorrectAnswer returns correct answer for a current reviewed card.
@@ -69,6 +108,48 @@ func (s *ReviewSession) CorrectAnswer() string {
 	return card.Answer()
 }
 
+// CorrectSides returns correct answers for each side of a current
+// reviewed card.
+func (s *ReviewSession) CorrectSides() []string {
+	card := s.currentCard()
+	if card == nil {
+		return nil
+	}
+
+	return card.Sides
+}
+
+// SubmitSides records user's answers for each side of a current card
+// and returns correctness of each side. Sides without an answer are
+// incorrect. Thinking time of the review is measured up to the first
+// submission.
+func (s *ReviewSession) SubmitSides(answers []string) []bool {
+	if s.answeredAt.IsZero() {
+		s.answeredAt = timeNow(s.clock)
+	}
+	s.answer = strings.Join(answers, " ")
+
+	sides := s.CorrectSides()
+	result := make([]bool, len(sides))
+	for idx, side := range sides {
+		result[idx] = idx < len(answers) && normalizeAnswer(answers[idx]) == normalizeAnswer(side)
+	}
+
+	return result
+}
+
+// SubmitAnswer records user's answer for a current card and returns
+// correct answer. Thinking time of the review is measured up to the
+// first submission.
+func (s *ReviewSession) SubmitAnswer(answer string) string {
+	if s.answeredAt.IsZero() {
+		s.answeredAt = timeNow(s.clock)
+	}
+	s.answer = answer
+
+	return s.CorrectAnswer()
+}
+
 // Again re-queues current card back for review.
 func (s *ReviewSession) Again() error {
 	card := s.currentCard()
@@ -76,30 +157,74 @@ func (s *ReviewSession) Again() error {
 		return errors.New("no cards in queue")
 	}
 
-	s.queue = s.queue[1:]
-	s.queue = append(s.queue, card.Question)
-	return nil
+	s.queue = append(s.queue[1:], s.queue[0])
+
+	interval := card.interval()
+	return s.logReview(card, ReviewScoreAgain, 0, interval, interval)
 }
 
 // Rate assign rating to a current card and removes it from the queue if rating > 0.
-func (s *ReviewSession) Rate(rating float64) error {
+func (s *ReviewSession) Rate(rating float64, score ReviewScore) error {
 	card := s.currentCard()
 	if card == nil {
 		return errors.New("no cards in queue")
 	}
 
 	s.queue = s.queue[1:]
+	prevInterval := card.interval()
 	card.Advance(rating)
-	return s.statsSaver(card)
+	if err := s.statsSaver(card); err != nil {
+		return err
+	}
+
+	return s.logReview(card, score, rating, prevInterval, card.interval())
+}
+
+func (s *ReviewSession) logReview(
+	card *CardWithStats,
+	score ReviewScore,
+	rating, prevInterval, interval float64,
+) error {
+	now := timeNow(s.clock)
+	answeredAt := s.answeredAt
+	if answeredAt.IsZero() {
+		answeredAt = now
+	}
+
+	review := &Review{
+		Card:             card.ID,
+		SessionStartedAt: s.startedAt,
+		ReviewedAt:       now,
+		Score:            score,
+		Rating:           rating,
+		Answer:           s.answer,
+		Elapsed:          answeredAt.Sub(s.shownAt),
+		PrevInterval:     prevInterval,
+		Interval:         interval,
+		Algorithm:        card.Algorithm(),
+	}
+
+	s.shownAt = now
+	s.answeredAt = time.Time{}
+	s.answer = ""
+
+	if s.reviewLog == nil {
+		return nil
+	}
+
+	return s.reviewLog(review)
+}
+
+// normalizeAnswer collapses whitespace of an answer, e.g. ideographic
+// spaces typed by IME.
+func normalizeAnswer(answer string) string {
+	return strings.Join(strings.Fields(answer), " ")
 }
 
 func (s *ReviewSession) currentCard() *CardWithStats {
-	question := s.Next()
-	for _, c := range s.cards {
-		if c.Question == question {
-			return &c
-		}
+	if len(s.queue) == 0 {
+		return nil
 	}
 
-	return nil
+	return &s.cards[s.queue[0]]
 }
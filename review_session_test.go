@@ -2,25 +2,37 @@ package leaf
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestReviewSession(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(100, 0))
 	cards := []CardWithStats{
-		{Card{"foo", "foo", []string{"bar"}}, NewStats(SRSSupermemo2PlusCustom)},
-		{Card{"bar", "foo", []string{"baz"}}, NewStats(SRSSupermemo2PlusCustom)},
+		{Card{"1", "foo", "foo", []string{"bar"}, "", nil}, &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}},
+		{Card{"2", "bar", "foo", []string{"baz"}, "", nil}, &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}},
 	}
 
 	stats := make(map[string]*Stats)
-	s := NewReviewSession(cards, RatingTypeAuto, func(card *CardWithStats) error {
-		stats[card.Question] = card.Stats
-		return nil
-	})
+	reviews := make([]*Review, 0)
+	s := NewReviewSession(
+		cards,
+		RatingTypeAuto,
+		func(card *CardWithStats) error {
+			stats[card.Question] = card.Stats
+			return nil
+		},
+		func(review *Review) error {
+			reviews = append(reviews, review)
+			return nil
+		},
+		clock,
+	)
 
 	t.Run("StartedAt", func(t *testing.T) {
-		assert.NotNil(t, s.StartedAt())
+		assert.Equal(t, clock.Now(), s.StartedAt())
 	})
 
 	t.Run("RatingType", func(t *testing.T) {
@@ -43,14 +55,22 @@ func TestReviewSession(t *testing.T) {
 		assert.Equal(t, "bar", s.CorrectAnswer())
 	})
 
+	t.Run("SubmitAnswer", func(t *testing.T) {
+		clock.Advance(5 * time.Second)
+		assert.Equal(t, "bar", s.SubmitAnswer("baz"))
+		assert.Equal(t, 2, s.Left())
+	})
+
 	t.Run("Rate - incorrect", func(t *testing.T) {
+		clock.Advance(time.Second)
 		require.NoError(t, s.Again())
 		assert.Equal(t, 2, s.Left())
 		assert.Equal(t, "bar", s.Next())
 	})
 
 	t.Run("Rate - correct", func(t *testing.T) {
-		require.NoError(t, s.Rate(1))
+		assert.Equal(t, "baz", s.SubmitAnswer("baz"))
+		require.NoError(t, s.Rate(1, ReviewScoreEasy))
 		assert.Equal(t, 1, s.Left())
 		assert.Equal(t, "foo", s.Next())
 	})
@@ -63,7 +83,7 @@ func TestReviewSession(t *testing.T) {
 	})
 
 	t.Run("Answer - finish session", func(t *testing.T) {
-		require.NoError(t, s.Rate(0))
+		require.NoError(t, s.Rate(0, ReviewScoreHard))
 		assert.Equal(t, 0, s.Left())
 	})
 
@@ -74,4 +94,82 @@ func TestReviewSession(t *testing.T) {
 	barStats := stats["bar"].SRSAlgorithm.(*Supermemo2PlusCustom)
 	assert.InDelta(t, 0.28, barStats.Difficulty, 0.01)
 	assert.InDelta(t, 0.37, barStats.Interval, 0.01)
+
+	require.Len(t, reviews, 7)
+	first := reviews[0]
+	assert.Equal(t, "1", first.Card)
+	assert.Equal(t, ReviewScoreAgain, first.Score)
+	assert.Equal(t, "baz", first.Answer)
+	assert.Equal(t, s.StartedAt(), first.SessionStartedAt)
+	assert.Equal(t, time.Unix(106, 0), first.ReviewedAt)
+	assert.Equal(t, 5*time.Second, first.Elapsed)
+	assert.Equal(t, SRSSupermemo2PlusCustom, string(first.Algorithm))
+	assert.InDelta(t, 0.2, first.PrevInterval, 0.01)
+	assert.InDelta(t, 0.2, first.Interval, 0.01)
+
+	second := reviews[1]
+	assert.Equal(t, "2", second.Card)
+	assert.Equal(t, ReviewScoreEasy, second.Score)
+	assert.InDelta(t, 1, second.Rating, 0.01)
+	assert.Equal(t, "baz", second.Answer)
+	assert.InDelta(t, 0.2, second.PrevInterval, 0.01)
+	assert.InDelta(t, 0.37, second.Interval, 0.01)
+
+	last := reviews[6]
+	assert.Equal(t, "1", last.Card)
+	assert.Equal(t, ReviewScoreHard, last.Score)
+	assert.Empty(t, last.Answer)
+}
+
+func TestReviewSessionSides(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	cards := []CardWithStats{
+		{Card{"1", "/emphasis/", "/emphasis/", []string{"/emphasis/", "side 2"}, "", nil}, &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}},
+	}
+
+	reviews := make([]*Review, 0)
+	s := NewReviewSession(cards, RatingTypeAuto, func(card *CardWithStats) error { return nil }, func(review *Review) error {
+		reviews = append(reviews, review)
+		return nil
+	}, clock)
+
+	assert.Equal(t, []string{"/emphasis/", "side 2"}, s.CorrectSides())
+	assert.Equal(t, []bool{true, false}, s.SubmitSides([]string{"/emphasis/", "side"}))
+	assert.Equal(t, []bool{true, true}, s.SubmitSides([]string{" /emphasis/", "side\u3000 2"}))
+	assert.Equal(t, []bool{false, false}, s.SubmitSides(nil))
+
+	require.NoError(t, s.Rate(0.69, ReviewScoreHard))
+	require.Len(t, reviews, 1)
+	assert.Empty(t, reviews[0].Answer)
+	assert.Nil(t, s.CorrectSides())
+}
+
+func TestReviewSessionDuplicates(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	cards := []CardWithStats{
+		{Card{"1", "bank", "bank", []string{"river side"}, "", nil}, &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}},
+		{Card{"2", "bank", "bank", []string{"money"}, "", nil}, &Stats{NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())}},
+	}
+
+	saved := make([]string, 0)
+	s := NewReviewSession(cards, RatingTypeAuto, func(card *CardWithStats) error {
+		saved = append(saved, card.ID)
+		return nil
+	}, nil, clock)
+
+	assert.Equal(t, "1", s.CardID())
+	assert.Equal(t, "river side", s.CorrectAnswer())
+	require.NoError(t, s.Again())
+
+	assert.Equal(t, "bank", s.Next())
+	assert.Equal(t, "2", s.CardID())
+	assert.Equal(t, "money", s.CorrectAnswer())
+	require.NoError(t, s.Rate(1, ReviewScoreEasy))
+
+	assert.Equal(t, "1", s.CardID())
+	assert.Equal(t, "river side", s.CorrectAnswer())
+	require.NoError(t, s.Rate(1, ReviewScoreEasy))
+
+	assert.Equal(t, []string{"2", "1"}, saved)
+	assert.Empty(t, s.CardID())
 }
@@ -0,0 +1,183 @@
+package leaf
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ForgettingCurve returns probability of recall after elapsed days
+// for a memory with a given stability in days. Stability is an
+// interval after which recall probability drops to 90%.
+type ForgettingCurve func(elapsed, stability float64) float64
+
+// ExponentialCurve is an exponential forgetting curve.
+func ExponentialCurve(elapsed, stability float64) float64 {
+	return math.Exp(math.Log(0.9) * elapsed / stability)
+}
+
+// PowerCurve is a power law forgetting curve used by FSRS.
+func PowerCurve(elapsed, stability float64) float64 {
+	return math.Pow(1+19.0/81*elapsed/stability, -0.5)
+}
+
+// LearnerModel defines memory behaviour of a synthetic learner.
+type LearnerModel struct {
+	// Curve defines decay of recall probability between reviews.
+	Curve ForgettingCurve
+	// InitialStability is a stability of a newly learned card in days.
+	InitialStability float64
+	// Growth is a stability multiplier for successful reviews.
+	Growth float64
+	// LapseFactor is a stability multiplier for failed reviews.
+	LapseFactor float64
+}
+
+// DefaultLearnerModel returns LearnerModel with an exponential curve
+// and moderate memory growth.
+func DefaultLearnerModel() LearnerModel {
+	return LearnerModel{ExponentialCurve, 1, 2.5, 0.3}
+}
+
+// SimulationConfig defines parameters of a simulated review schedule.
+type SimulationConfig struct {
+	// Days is an amount of simulated days.
+	Days int
+	// Cards is a total amount of cards in a deck.
+	Cards int
+	// NewPerDay is a maximum amount of cards learned per day.
+	NewPerDay int
+	// ReviewBudget is a maximum amount of reviews per day including
+	// new cards, 0 means unlimited.
+	ReviewBudget int
+	// Learner defines memory model of a simulated learner.
+	Learner LearnerModel
+	// Params contains algorithm parameters.
+	Params SRSParams
+	// Seed is a seed for recall outcomes, simulations with a same
+	// seed produce same results.
+	Seed int64
+}
+
+// SimulationDay contains workload and retention of a single simulated day.
+type SimulationDay struct {
+	Day       int     `json:"day"`
+	Reviews   int     `json:"reviews"`
+	New       int     `json:"new"`
+	Lapses    int     `json:"lapses"`
+	Retention float64 `json:"retention"`
+}
+
+// SimulationResult contains outcome of a simulation for a single algorithm.
+type SimulationResult struct {
+	Algorithm        SRS             `json:"algorithm"`
+	Days             []SimulationDay `json:"days"`
+	TotalReviews     int             `json:"total_reviews"`
+	AverageRetention float64         `json:"average_retention"`
+}
+
+type simulatedCard struct {
+	name           string
+	stats          *Stats
+	stability      float64
+	lastReviewedAt time.Time
+}
+
+// Simulate runs synthetic learner against a given algorithm. Each day
+// learner reviews due cards in the same order as ReviewSession would
+// and then learns new cards within a review budget. Failed recalls
+// are rated by HarshRater as a single mistake. Retention is an
+// average recall probability of learned cards at the end of the day.
+func Simulate(srs SRS, config SimulationConfig) (*SimulationResult, error) {
+	if config.Days <= 0 || config.Cards <= 0 || config.NewPerDay <= 0 {
+		return nil, errors.New("simulate: days, cards and new cards per day should be positive")
+	}
+
+	learner := config.Learner
+	if learner.Curve == nil || learner.InitialStability <= 0 || learner.Growth <= 0 || learner.LapseFactor <= 0 {
+		return nil, errors.New("simulate: invalid learner model")
+	}
+
+	if _, err := NewStats(srs, SystemClock, config.Params); err != nil {
+		return nil, err
+	}
+
+	rnd := rand.New(rand.NewSource(config.Seed))
+	clock := NewSimulatedClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	result := &SimulationResult{Algorithm: srs, Days: make([]SimulationDay, 0, config.Days)}
+
+	cards := make([]*simulatedCard, 0, config.Cards)
+	for day := 0; day < config.Days; day++ {
+		rater := HarshRater()
+		stats := SimulationDay{Day: day + 1}
+
+		due := make([]*simulatedCard, 0)
+		for _, card := range cards {
+			if card.stats.IsReady(clock) {
+				due = append(due, card)
+			}
+		}
+		sort.Slice(due, func(i, j int) bool {
+			return due[j].stats.Less(due[i].stats.SRSAlgorithm)
+		})
+
+		for _, card := range due {
+			if config.ReviewBudget > 0 && stats.Reviews == config.ReviewBudget {
+				break
+			}
+
+			elapsed := clock.Now().Sub(card.lastReviewedAt).Hours() / 24
+			if rnd.Float64() < learner.Curve(elapsed, card.stability) {
+				card.stability *= learner.Growth
+			} else {
+				card.stability = math.Max(card.stability*learner.LapseFactor, learner.InitialStability)
+				rater.Rate(card.name, ReviewScoreAgain)
+				stats.Lapses++
+			}
+
+			card.stats.Advance(rater.Rate(card.name, ReviewScoreEasy))
+			card.lastReviewedAt = clock.Now()
+			stats.Reviews++
+		}
+
+		for stats.New < config.NewPerDay && len(cards) < config.Cards {
+			if config.ReviewBudget > 0 && stats.Reviews == config.ReviewBudget {
+				break
+			}
+
+			s, err := NewStats(srs, clock, config.Params)
+			if err != nil {
+				return nil, err
+			}
+
+			card := &simulatedCard{
+				name:           strconv.Itoa(len(cards)),
+				stats:          s,
+				stability:      learner.InitialStability,
+				lastReviewedAt: clock.Now(),
+			}
+			card.stats.Advance(rater.Rate(card.name, ReviewScoreEasy))
+			cards = append(cards, card)
+			stats.New++
+			stats.Reviews++
+		}
+
+		clock.Advance(24 * time.Hour)
+		if len(cards) > 0 {
+			for _, card := range cards {
+				elapsed := clock.Now().Sub(card.lastReviewedAt).Hours() / 24
+				stats.Retention += learner.Curve(elapsed, card.stability)
+			}
+			stats.Retention /= float64(len(cards))
+		}
+
+		result.Days = append(result.Days, stats)
+		result.TotalReviews += stats.Reviews
+		result.AverageRetention += stats.Retention / float64(config.Days)
+	}
+
+	return result, nil
+}
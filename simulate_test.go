@@ -0,0 +1,82 @@
+package leaf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForgettingCurves(t *testing.T) {
+	assert.InDelta(t, 1, ExponentialCurve(0, 1), 0.01)
+	assert.InDelta(t, 0.9, ExponentialCurve(1, 1), 0.01)
+	assert.InDelta(t, 0.9, ExponentialCurve(10, 10), 0.01)
+	assert.InDelta(t, 0.59, ExponentialCurve(5, 1), 0.01)
+
+	assert.InDelta(t, 1, PowerCurve(0, 1), 0.01)
+	assert.InDelta(t, 0.9, PowerCurve(1, 1), 0.01)
+	assert.InDelta(t, 0.9, PowerCurve(10, 10), 0.01)
+	assert.InDelta(t, 0.68, PowerCurve(5, 1), 0.01)
+}
+
+func TestSimulate(t *testing.T) {
+	config := SimulationConfig{
+		Days:         60,
+		Cards:        100,
+		NewPerDay:    5,
+		ReviewBudget: 20,
+		Learner:      DefaultLearnerModel(),
+		Seed:         1,
+	}
+
+	for _, srs := range AvailableSRS() {
+		t.Run(string(srs), func(t *testing.T) {
+			res, err := Simulate(srs, config)
+			require.NoError(t, err)
+			assert.Equal(t, srs, res.Algorithm)
+			require.Len(t, res.Days, 60)
+
+			total, learned := 0, 0
+			for idx, day := range res.Days {
+				assert.Equal(t, idx+1, day.Day)
+				assert.True(t, day.Reviews <= 20)
+				assert.True(t, day.New <= 5)
+				assert.True(t, day.Retention > 0 && day.Retention <= 1)
+				total += day.Reviews
+				learned += day.New
+			}
+			assert.Equal(t, total, res.TotalReviews)
+			assert.True(t, learned <= 100)
+			assert.True(t, res.AverageRetention > 0 && res.AverageRetention <= 1)
+
+			again, err := Simulate(srs, config)
+			require.NoError(t, err)
+			assert.Equal(t, res, again)
+		})
+	}
+
+	t.Run("unlimited budget", func(t *testing.T) {
+		config := config
+		config.ReviewBudget = 0
+		res, err := Simulate(SRSSupermemo2, config)
+		require.NoError(t, err)
+
+		learned := 0
+		for _, day := range res.Days {
+			learned += day.New
+		}
+		assert.Equal(t, 100, learned)
+		assert.Equal(t, 5, res.Days[0].Reviews)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := Simulate("foo", config)
+		assert.Error(t, err)
+
+		_, err = Simulate(SRSSupermemo2, SimulationConfig{Days: 1, Cards: 1, NewPerDay: 1})
+		assert.Error(t, err)
+
+		_, err = Simulate(SRSSupermemo2, SimulationConfig{Learner: DefaultLearnerModel()})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,342 @@
+package leaf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// sqliteDB is a minimal read-only reader of SQLite database files.
+// Only table b-trees are traversed, which is enough to read all rows
+// of a table without SQL. Only UTF-8 databases are supported.
+type sqliteDB struct {
+	data     []byte
+	pageSize int
+	usable   int
+}
+
+// sqliteRow is a decoded table row. Values are nil, int64, float64,
+// string or []byte.
+type sqliteRow struct {
+	rowid  int64
+	values []interface{}
+}
+
+const sqliteMagic = "SQLite format 3\x00"
+
+// openSQLite validates header of a database file.
+func openSQLite(data []byte) (*sqliteDB, error) {
+	if len(data) < 100 || !bytes.HasPrefix(data, []byte(sqliteMagic)) {
+		return nil, fmt.Errorf("sqlite: invalid database header")
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("sqlite: invalid page size %d", pageSize)
+	}
+
+	if encoding := binary.BigEndian.Uint32(data[56:60]); encoding > 1 {
+		return nil, fmt.Errorf("sqlite: unsupported text encoding %d", encoding)
+	}
+
+	// usable size of a page is at least 480 bytes by the file format
+	usable := pageSize - int(data[20])
+	if usable < 480 {
+		return nil, fmt.Errorf("sqlite: invalid reserved space %d", data[20])
+	}
+
+	return &sqliteDB{data, pageSize, usable}, nil
+}
+
+// table returns all rows of a table in rowid order.
+func (db *sqliteDB) table(name string) ([]sqliteRow, error) {
+	schema, err := db.rows(1)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite_schema columns are type, name, tbl_name, rootpage and sql
+	for _, row := range schema {
+		if row.text(0) == "table" && row.text(1) == name {
+			return db.rows(int(row.int(3)))
+		}
+	}
+
+	return nil, fmt.Errorf("sqlite: table %s is not found", name)
+}
+
+// rows returns rows of a table b-tree with a given root page.
+func (db *sqliteDB) rows(root int) ([]sqliteRow, error) {
+	result := make([]sqliteRow, 0)
+	visited := make(map[int]bool)
+
+	var walk func(pageNum int) error
+	walk = func(pageNum int) error {
+		if visited[pageNum] {
+			return fmt.Errorf("sqlite: page %d is referenced twice", pageNum)
+		}
+		visited[pageNum] = true
+
+		page, err := db.page(pageNum)
+		if err != nil {
+			return err
+		}
+
+		offset := 0
+		if pageNum == 1 {
+			offset = 100
+		}
+
+		kind := page[offset]
+		cells := int(binary.BigEndian.Uint16(page[offset+3 : offset+5]))
+		header := 8
+		if kind == 0x05 {
+			header = 12
+		} else if kind != 0x0d {
+			return fmt.Errorf("sqlite: unexpected page type %d on page %d", kind, pageNum)
+		}
+
+		if offset+header+cells*2 > len(page) {
+			return fmt.Errorf("sqlite: malformed page %d", pageNum)
+		}
+
+		for idx := 0; idx < cells; idx++ {
+			ptr := offset + header + idx*2
+			cell := int(binary.BigEndian.Uint16(page[ptr : ptr+2]))
+			if cell+4 > len(page) {
+				return fmt.Errorf("sqlite: malformed page %d", pageNum)
+			}
+
+			if kind == 0x05 {
+				if err := walk(int(binary.BigEndian.Uint32(page[cell : cell+4]))); err != nil {
+					return err
+				}
+				continue
+			}
+
+			row, err := db.cell(page, cell, visited)
+			if err != nil {
+				return err
+			}
+			result = append(result, row)
+		}
+
+		if kind == 0x05 {
+			return walk(int(binary.BigEndian.Uint32(page[offset+8 : offset+12])))
+		}
+
+		return nil
+	}
+
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// page returns content of a 1-based page number.
+func (db *sqliteDB) page(pageNum int) ([]byte, error) {
+	start := (pageNum - 1) * db.pageSize
+	if pageNum < 1 || start+db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("sqlite: page %d is out of range", pageNum)
+	}
+
+	return db.data[start : start+db.pageSize], nil
+}
+
+// cell decodes a table leaf cell at a given offset. Payloads that
+// don't fit into a page are read from overflow pages, each page can
+// be visited only once.
+func (db *sqliteDB) cell(page []byte, offset int, visited map[int]bool) (sqliteRow, error) {
+	size, n := sqliteVarint(page[offset:])
+	if n == 0 {
+		return sqliteRow{}, fmt.Errorf("sqlite: malformed cell")
+	}
+	offset += n
+
+	rowid, n := sqliteVarint(page[offset:])
+	if n == 0 {
+		return sqliteRow{}, fmt.Errorf("sqlite: malformed cell")
+	}
+	offset += n
+
+	// payload can't be larger than a database itself
+	if size > uint64(len(db.data)) {
+		return sqliteRow{}, fmt.Errorf("sqlite: malformed cell")
+	}
+
+	total := int(size)
+	local := total
+	if maxLocal := db.usable - 35; total > maxLocal {
+		minLocal := (db.usable-12)*32/255 - 23
+		local = minLocal + (total-minLocal)%(db.usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+
+	if offset+local > len(page) || (local < total && offset+local+4 > len(page)) {
+		return sqliteRow{}, fmt.Errorf("sqlite: malformed cell")
+	}
+
+	payload := make([]byte, 0, total)
+	payload = append(payload, page[offset:offset+local]...)
+	if local < total {
+		next := int(binary.BigEndian.Uint32(page[offset+local:]))
+		for len(payload) < total {
+			if visited[next] {
+				return sqliteRow{}, fmt.Errorf("sqlite: page %d is referenced twice", next)
+			}
+			visited[next] = true
+
+			overflow, err := db.page(next)
+			if err != nil {
+				return sqliteRow{}, err
+			}
+
+			chunk := overflow[4:db.usable]
+			if left := total - len(payload); len(chunk) > left {
+				chunk = chunk[:left]
+			}
+			payload = append(payload, chunk...)
+			next = int(binary.BigEndian.Uint32(overflow[:4]))
+		}
+	}
+
+	values, err := sqliteRecord(payload)
+	if err != nil {
+		return sqliteRow{}, err
+	}
+
+	return sqliteRow{int64(rowid), values}, nil
+}
+
+// sqliteRecord decodes values of a record payload.
+func sqliteRecord(payload []byte) ([]interface{}, error) {
+	headerSize, n := sqliteVarint(payload)
+	if n == 0 || headerSize > uint64(len(payload)) {
+		return nil, fmt.Errorf("sqlite: malformed record")
+	}
+
+	types := make([]uint64, 0)
+	for pos := n; pos < int(headerSize); {
+		t, n := sqliteVarint(payload[pos:int(headerSize)])
+		if n == 0 {
+			return nil, fmt.Errorf("sqlite: malformed record")
+		}
+		types = append(types, t)
+		pos += n
+	}
+
+	values := make([]interface{}, len(types))
+	body := payload[int(headerSize):]
+	for idx, t := range types {
+		size := uint64(0)
+		switch {
+		case t >= 12:
+			size = (t - 12) / 2
+		case t >= 1 && t <= 4:
+			size = t
+		case t == 5:
+			size = 6
+		case t == 6 || t == 7:
+			size = 8
+		}
+
+		if size > uint64(len(body)) {
+			return nil, fmt.Errorf("sqlite: malformed record")
+		}
+		data := body[:size]
+		body = body[size:]
+
+		switch {
+		case t == 0:
+			values[idx] = nil
+		case t <= 6:
+			// big-endian two's complement integers
+			v := int64(int8(data[0]))
+			for _, b := range data[1:] {
+				v = v<<8 | int64(b)
+			}
+			values[idx] = v
+		case t == 7:
+			values[idx] = math.Float64frombits(binary.BigEndian.Uint64(data))
+		case t == 8:
+			values[idx] = int64(0)
+		case t == 9:
+			values[idx] = int64(1)
+		case t >= 12 && t%2 == 0:
+			values[idx] = append([]byte{}, data...)
+		case t >= 13:
+			values[idx] = string(data)
+		default:
+			return nil, fmt.Errorf("sqlite: unsupported serial type %d", t)
+		}
+	}
+
+	return values, nil
+}
+
+// sqliteVarint decodes a variable-length integer, zero length is
+// returned for truncated values.
+func sqliteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for idx := 0; idx < 8; idx++ {
+		if idx >= len(b) {
+			return 0, 0
+		}
+
+		v = v<<7 | uint64(b[idx]&0x7f)
+		if b[idx] < 0x80 {
+			return v, idx + 1
+		}
+	}
+
+	if len(b) < 9 {
+		return 0, 0
+	}
+
+	return v<<8 | uint64(b[8]), 9
+}
+
+// int returns integer value of a column, rowid is returned for NULL
+// values of the first column which is usually an alias of the rowid.
+func (row sqliteRow) int(idx int) int64 {
+	if idx >= len(row.values) {
+		return 0
+	}
+
+	switch v := row.values[idx].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case nil:
+		if idx == 0 {
+			return row.rowid
+		}
+	}
+
+	return 0
+}
+
+// text returns text value of a column.
+func (row sqliteRow) text(idx int) string {
+	if idx >= len(row.values) {
+		return ""
+	}
+
+	switch v := row.values[idx].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+
+	return ""
+}
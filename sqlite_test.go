@@ -0,0 +1,155 @@
+package leaf
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteVarint(t *testing.T) {
+	v, n := sqliteVarint([]byte{0x7f})
+	assert.Equal(t, uint64(127), v)
+	assert.Equal(t, 1, n)
+
+	v, n = sqliteVarint([]byte{0x81, 0x00})
+	assert.Equal(t, uint64(128), v)
+	assert.Equal(t, 2, n)
+
+	v, n = sqliteVarint([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	assert.Equal(t, ^uint64(0), v)
+	assert.Equal(t, 9, n)
+
+	_, n = sqliteVarint([]byte{0x81})
+	assert.Equal(t, 0, n)
+}
+
+func TestSQLiteRecord(t *testing.T) {
+	// header: size 5, NULL, int8, float64, text of 3 bytes
+	payload := []byte{5, 0, 1, 7, 19, 0xfe, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0, 'f', 'o', 'o'}
+	values, err := sqliteRecord(payload)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{nil, int64(-2), 1.5, "foo"}, values)
+
+	_, err = sqliteRecord(payload[:10])
+	assert.EqualError(t, err, "sqlite: malformed record")
+}
+
+func TestSQLiteTable(t *testing.T) {
+	archive, err := zip.OpenReader("./fixtures/anki.apkg")
+	require.NoError(t, err)
+	defer archive.Close()
+
+	var data []byte
+	for _, f := range archive.File {
+		if f.Name == "collection.anki2" {
+			data, err = readZipFile(f)
+			require.NoError(t, err)
+		}
+	}
+
+	db, err := openSQLite(data)
+	require.NoError(t, err)
+
+	notes, err := db.table("notes")
+	require.NoError(t, err)
+	require.Len(t, notes, 67)
+	assert.Equal(t, int64(1546300800000), notes[0].int(0))
+	assert.Equal(t, "<b>食べる</b>\x1fto eat<br>to consume", notes[0].text(6))
+	// payload of a long note is stored in overflow pages
+	assert.Len(t, notes[65].text(7), 3005)
+
+	_, err = db.table("foo")
+	assert.EqualError(t, err, "sqlite: table foo is not found")
+
+	_, err = openSQLite([]byte("foo"))
+	assert.EqualError(t, err, "sqlite: invalid database header")
+}
+
+func TestSQLiteCorpus(t *testing.T) {
+	// generated by sqlite3 with 512 bytes pages
+	data, err := ioutil.ReadFile("./fixtures/sqlite.db")
+	require.NoError(t, err)
+
+	db, err := openSQLite(data)
+	require.NoError(t, err)
+
+	t.Run("interior pages", func(t *testing.T) {
+		rows, err := db.table("deep")
+		require.NoError(t, err)
+		require.Len(t, rows, 120)
+
+		for idx, row := range rows {
+			assert.Equal(t, int64(idx+1)<<40+int64(idx), row.int(0))
+			assert.Equal(t, strings.Repeat(fmt.Sprintf("%03d", idx), 66), row.text(1))
+		}
+
+		// root and its first child are both interior pages
+		root, err := db.page(2)
+		require.NoError(t, err)
+		assert.Equal(t, byte(0x05), root[0])
+
+		cell := binary.BigEndian.Uint16(root[12:14])
+		child, err := db.page(int(binary.BigEndian.Uint32(root[cell:])))
+		require.NoError(t, err)
+		assert.Equal(t, byte(0x05), child[0])
+	})
+
+	t.Run("overflow pages", func(t *testing.T) {
+		rows, err := db.table("blobs")
+		require.NoError(t, err)
+
+		sizes := []int{0, 1, 400, 470, 471, 1000, 5000, 20000}
+		require.Len(t, rows, len(sizes))
+
+		for idx, row := range rows {
+			blob := make([]byte, sizes[idx])
+			for j := range blob {
+				blob[j] = byte(j*7 + idx)
+			}
+
+			assert.Equal(t, int64(idx+1), row.int(0))
+			assert.Equal(t, blob, row.values[1])
+			assert.Equal(t, strings.Repeat("é", sizes[idx]/2), row.text(2))
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		corrupted := append([]byte{}, data...)
+		corrupted[20] = 64
+		_, err := openSQLite(corrupted)
+		assert.EqualError(t, err, "sqlite: invalid reserved space 64")
+
+		// deep table root points to itself
+		corrupted = append([]byte{}, data...)
+		binary.BigEndian.PutUint32(corrupted[512+8:], 2)
+		db, err := openSQLite(corrupted)
+		require.NoError(t, err)
+		_, err = db.table("deep")
+		assert.EqualError(t, err, "sqlite: page 2 is referenced twice")
+	})
+}
+
+func FuzzSQLite(f *testing.F) {
+	data, err := ioutil.ReadFile("./fixtures/sqlite.db")
+	require.NoError(f, err)
+
+	f.Add(data)
+	f.Add(data[:1024])
+	f.Add(data[:len(data)-512])
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		db, err := openSQLite(data)
+		if err != nil {
+			return
+		}
+
+		db.table("deep")
+		db.table("blobs")
+	})
+}
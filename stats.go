@@ -2,6 +2,7 @@ package leaf
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -41,27 +42,48 @@ const (
 	SRSSupermemo2PlusCustom = "sm2+c"
 	// SRSEbisu represents Ebisu algorithm
 	SRSEbisu = "ebisu"
+	// SRSFSRS represents FSRS algorithm
+	SRSFSRS = "fsrs"
 )
 
 // NewStats returns a new Stats initialized with provided algorithm
-// with default values. Supported values: sm2, sm2+, sm2+c. If smAlgo
-// is missing or unknown will default to Supermemo2PlusCustom.
-func NewStats(srs SRS) *Stats {
-	var sm SRSAlgorithm
-	switch srs {
-	case SRSSupermemo2:
-		sm = NewSupermemo2()
-	case SRSSupermemo2Plus:
-		sm = NewSupermemo2Plus()
-	case SRSEbisu:
-		sm = NewEbisu()
-	default:
-		sm = NewSupermemo2PlusCustom()
+// with default values. Algorithms are looked up in the registry,
+// built-in values: sm2, sm2+, sm2+c, ebisu, fsrs. Error is returned
+// for unknown algorithms and invalid parameters. Algorithm will use
+// provided clock for scheduling and deck parameters to adjust it's
+// curve, nil parameters are allowed.
+func NewStats(srs SRS, clock Clock, params SRSParams) (*Stats, error) {
+	factory, ok := lookupSRS(srs)
+	if !ok {
+		return nil, fmt.Errorf("srs: unknown algorithm %q", srs)
 	}
-	return &Stats{sm}
+
+	sm, err := factory(clock, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stats{sm}, nil
+}
+
+// Algorithm returns registered name of the stats algorithm, empty
+// value is returned for unknown algorithms.
+func (s Stats) Algorithm() SRS {
+	return algorithmName(s.SRSAlgorithm)
+}
+
+// interval returns current review interval in days.
+func (s Stats) interval() float64 {
+	state, err := extractReviewState(s.SRSAlgorithm)
+	if err != nil {
+		return 0
+	}
+
+	return state.interval
 }
 
-// IsReady signals whether card is read for review.
-func (s Stats) IsReady() bool {
-	return s.NextReviewAt().Before(time.Now())
+// IsReady signals whether card is due for review at the current
+// time of a given clock.
+func (s Stats) IsReady(clock Clock) bool {
+	return !s.NextReviewAt().After(timeNow(clock))
 }
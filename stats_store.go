@@ -1,75 +1,467 @@
 package leaf
 
 import (
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 
 	bolt "go.etcd.io/bbolt"
 )
 
+// statsSchemaVersion defines current version of the stats record format.
+const statsSchemaVersion = 1
+
+// reviewsBucket stores review logs of all decks, name is prefixed
+// with NUL to avoid collisions with deck names.
+var reviewsBucket = []byte("\x00reviews")
+
+// legacyBucket marks decks with records moved from legacy keys.
+var legacyBucket = []byte("\x00legacy")
+
+var errStopRange = errors.New("range stopped")
+
 // StatsStore defines storage interface that is used for storing review stats.
 type StatsStore interface {
 	io.Closer
-	// RangeStats iterates over all stats in a Store. DB records will be boxed to provide algoritm.
-	RangeStats(deck string, srs SRS, rangeFunc func(card string, stats *Stats) bool) error
+	ReviewLog
+	// RangeStats iterates over all stats in a Store. DB records will
+	// be boxed to provide algoritm configured with provided params and
+	// scheduled using provided clock. Records saved with a different
+	// algorithm are handled according to the MismatchPolicy, untagged
+	// records are tagged with provided algorithm. Records that are
+	// not migrated are skipped and reported with a MismatchError
+	// once the rest of records is iterated.
+	RangeStats(deck string, srs SRS, clock Clock, params SRSParams, rangeFunc func(card string, stats *Stats) bool) error
+	// RangeRecords iterates over all stats in a Store boxed to an
+	// algorithm they were saved with. Untagged records will be boxed
+	// to a fallback algorithm and reported with an empty algorithm.
+	// Records saved with an unregistered algorithm are reported with
+	// nil stats. Records are never modified.
+	RangeRecords(deck string, fallback SRS, clock Clock, params SRSParams, rangeFunc func(card string, srs SRS, stats *Stats) bool) error
 	// SaveStats saves stats for a card.
 	SaveStats(deck string, card string, stats *Stats) error
+	// Decks returns names of decks that have stats records.
+	Decks() ([]string, error)
+	// DeleteStats removes stats of a card.
+	DeleteStats(deck string, card string) error
+	// RenameCards moves stats and review log records of a deck
+	// from old card keys to new ones. Records that already exist
+	// under a new key are not overwritten.
+	RenameCards(deck string, keys map[string]string) error
+	// RenameLegacyCards moves records of a deck saved using legacy
+	// keys like RenameCards. Records are moved once for each deck,
+	// further calls don't modify the store.
+	RenameLegacyCards(deck string, keys map[string]string) error
+}
+
+// MismatchPolicy defines how StatsStore handles records saved with
+// an algorithm different from the requested one.
+type MismatchPolicy int
+
+const (
+	// MismatchRefuse skips mismatched records and reports them with
+	// a MismatchError.
+	MismatchRefuse MismatchPolicy = iota
+	// MismatchMigrate converts mismatched records using MigrateStats
+	// and saves converted stats. Records saved with an unregistered
+	// algorithm can't be converted and are handled as refused.
+	MismatchMigrate
+)
+
+// MismatchError is returned for decks that have stats saved with an
+// algorithm different from the requested one. Cards contains refused
+// records along with their algorithms.
+type MismatchError struct {
+	Deck  string
+	SRS   SRS
+	Cards map[string]SRS
+}
+
+func (e *MismatchError) Error() string {
+	cards := make([]string, 0, len(e.Cards))
+	for card, srs := range e.Cards {
+		cards = append(cards, fmt.Sprintf("%s (%s)", card, srs))
+	}
+	sort.Strings(cards)
+
+	return fmt.Sprintf(
+		"deck %s uses %s, stats for %d cards were saved with a different algorithm: %s",
+		e.Deck, e.SRS, len(cards), strings.Join(cards, ", "),
+	)
+}
+
+// statsRecord is a stored representation of card stats.
+type statsRecord struct {
+	Version   int             `json:"v"`
+	Algorithm SRS             `json:"srs"`
+	Stats     json.RawMessage `json:"stats"`
 }
 
 type boltStore struct {
-	bolt *bolt.DB
+	bolt   *bolt.DB
+	policy MismatchPolicy
 }
 
-// OpenBoltStore returns a new StatsStore implemented on top of BoltDB.
-func OpenBoltStore(filename string) (StatsStore, error) {
+// OpenBoltStore returns a new StatsStore implemented on top of
+// BoltDB. Provided policy defines how mismatched records are handled.
+func OpenBoltStore(filename string, policy MismatchPolicy) (StatsStore, error) {
 	db, err := bolt.Open(filename, 0600, nil)
 	if err != nil {
-		return nil, fmt.Errorf("db: %s", db)
+		return nil, fmt.Errorf("db: %s", err)
 	}
 
-	return &boltStore{db}, nil
+	return &boltStore{db, policy}, nil
 }
 
 func (db *boltStore) RangeStats(
 	deck string,
 	srs SRS,
+	clock Clock,
+	params SRSParams,
 	rangeFunc func(card string, stats *Stats) bool,
 ) error {
+	cards := make([]string, 0)
+	stats := make(map[string]*Stats)
+	mismatched := make(map[string]SRS)
+	untagged := make(map[string]*Stats)
+	err := db.RangeRecords(deck, srs, clock, params, func(card string, recordSRS SRS, s *Stats) bool {
+		cards = append(cards, card)
+		stats[card] = s
+		if recordSRS == "" {
+			untagged[card] = s
+		} else if recordSRS != srs {
+			mismatched[card] = recordSRS
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := db.saveStats(deck, untagged); err != nil {
+		return err
+	}
+
+	if db.policy == MismatchMigrate {
+		for card := range mismatched {
+			if stats[card] == nil {
+				continue
+			}
+
+			migrated, err := MigrateStats(stats[card], srs, clock, params)
+			if err != nil {
+				return err
+			}
+
+			if err := db.SaveStats(deck, card, migrated); err != nil {
+				return err
+			}
+			stats[card] = migrated
+			delete(mismatched, card)
+		}
+	}
+
+	for _, card := range cards {
+		if _, ok := mismatched[card]; ok {
+			continue
+		}
+
+		if !rangeFunc(card, stats[card]) {
+			break
+		}
+	}
+
+	if len(mismatched) > 0 {
+		return &MismatchError{deck, srs, mismatched}
+	}
+
+	return nil
+}
+
+func (db *boltStore) RangeRecords(
+	deck string,
+	fallback SRS,
+	clock Clock,
+	params SRSParams,
+	rangeFunc func(card string, srs SRS, stats *Stats) bool,
+) error {
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(deck))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(card, data []byte) error {
+			record := new(statsRecord)
+			if err := json.Unmarshal(data, record); err != nil {
+				return fmt.Errorf("json: %s", err)
+			}
+
+			algorithm := record.Algorithm
+			if record.Version == 0 {
+				record = &statsRecord{statsSchemaVersion, "", data}
+				algorithm = fallback
+			}
+
+			if _, ok := lookupSRS(algorithm); !ok && record.Algorithm != "" {
+				if !rangeFunc(string(card), record.Algorithm, nil) {
+					return errStopRange
+				}
+				return nil
+			}
+
+			s, err := NewStats(algorithm, clock, params)
+			if err != nil {
+				return err
+			}
+
+			if err := json.Unmarshal(record.Stats, s); err != nil {
+				return fmt.Errorf("json: %s", err)
+			}
+
+			if !rangeFunc(string(card), record.Algorithm, s) {
+				return errStopRange
+			}
+
+			return nil
+		})
+	})
+	if err == errStopRange {
+		return nil
+	}
+
+	return err
+}
+
+func (db *boltStore) SaveStats(deck string, card string, stats *Stats) error {
+	return db.saveStats(deck, map[string]*Stats{card: stats})
+}
+
+// saveStats saves stats of multiple cards in a single transaction.
+func (db *boltStore) saveStats(deck string, stats map[string]*Stats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
 	return db.bolt.Update(func(tx *bolt.Tx) error {
 		b, err := tx.CreateBucketIfNotExists([]byte(deck))
 		if err != nil {
 			return err
 		}
 
-		return b.ForEach(func(card, stats []byte) error {
-			s := NewStats(srs)
-			if err := json.Unmarshal(stats, s); err != nil {
+		for card, s := range stats {
+			srs := s.Algorithm()
+			if srs == "" {
+				return fmt.Errorf("db: unknown algorithm %T", s.SRSAlgorithm)
+			}
+
+			data, err := json.Marshal(s)
+			if err != nil {
 				return fmt.Errorf("json: %s", err)
 			}
 
-			if !rangeFunc(string(card), s) {
-				return nil
+			record, err := json.Marshal(&statsRecord{statsSchemaVersion, srs, data})
+			if err != nil {
+				return fmt.Errorf("json: %s", err)
 			}
 
+			if err := b.Put([]byte(card), record); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (db *boltStore) Decks() ([]string, error) {
+	decks := make([]string, 0)
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if string(name) != string(reviewsBucket) && string(name) != string(legacyBucket) {
+				decks = append(decks, string(name))
+			}
 			return nil
 		})
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return decks, nil
 }
 
-func (db *boltStore) SaveStats(deck string, card string, stats *Stats) error {
+func (db *boltStore) DeleteStats(deck string, card string) error {
 	return db.bolt.Update(func(tx *bolt.Tx) error {
-		b, err := tx.CreateBucketIfNotExists([]byte(deck))
+		b := tx.Bucket([]byte(deck))
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(card))
+	})
+}
+
+func (db *boltStore) RenameCards(deck string, keys map[string]string) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		return renameCards(tx, deck, keys)
+	})
+}
+
+func (db *boltStore) RenameLegacyCards(deck string, keys map[string]string) error {
+	renamed := false
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(legacyBucket)
+		renamed = b != nil && b.Get([]byte(deck)) != nil
+		return nil
+	})
+	if err != nil || renamed {
+		return err
+	}
+
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		if err := renameCards(tx, deck, keys); err != nil {
+			return err
+		}
+
+		b, err := tx.CreateBucketIfNotExists(legacyBucket)
 		if err != nil {
 			return err
 		}
 
-		data, err := json.Marshal(stats)
+		return b.Put([]byte(deck), []byte{1})
+	})
+}
+
+// renameCards moves stats and review log records of a deck within a
+// transaction.
+func renameCards(tx *bolt.Tx, deck string, keys map[string]string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if b := tx.Bucket([]byte(deck)); b != nil {
+		for from, to := range keys {
+			data := b.Get([]byte(from))
+			if data == nil || b.Get([]byte(to)) != nil {
+				continue
+			}
+
+			record := make([]byte, len(data))
+			copy(record, data)
+			if err := b.Put([]byte(to), record); err != nil {
+				return err
+			}
+			if err := b.Delete([]byte(from)); err != nil {
+				return err
+			}
+		}
+	}
+
+	reviews := tx.Bucket(reviewsBucket)
+	if reviews == nil {
+		return nil
+	}
+
+	b := reviews.Bucket([]byte(deck))
+	if b == nil {
+		return nil
+	}
+
+	renamed := make(map[string][]byte)
+	err := b.ForEach(func(key, data []byte) error {
+		review := new(Review)
+		if err := json.Unmarshal(data, review); err != nil {
+			return fmt.Errorf("json: %s", err)
+		}
+
+		to, ok := keys[review.Card]
+		if !ok {
+			return nil
+		}
+
+		review.Card = to
+		res, err := json.Marshal(review)
 		if err != nil {
 			return fmt.Errorf("json: %s", err)
 		}
+		renamed[string(key)] = res
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, data := range renamed {
+		if err := b.Put([]byte(key), data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db *boltStore) LogReview(review *Review) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		reviews, err := tx.CreateBucketIfNotExists(reviewsBucket)
+		if err != nil {
+			return err
+		}
+
+		b, err := reviews.CreateBucketIfNotExists([]byte(review.Deck))
+		if err != nil {
+			return err
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(review)
+		if err != nil {
+			return fmt.Errorf("json: %s", err)
+		}
+
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		return b.Put(key, data)
+	})
+}
+
+func (db *boltStore) RangeReviews(deck string, rangeFunc func(review *Review) bool) error {
+	return db.bolt.View(func(tx *bolt.Tx) error {
+		reviews := tx.Bucket(reviewsBucket)
+		if reviews == nil {
+			return nil
+		}
+
+		b := reviews.Bucket([]byte(deck))
+		if b == nil {
+			return nil
+		}
+
+		err := b.ForEach(func(_, data []byte) error {
+			review := new(Review)
+			if err := json.Unmarshal(data, review); err != nil {
+				return fmt.Errorf("json: %s", err)
+			}
+
+			if !rangeFunc(review) {
+				return errStopRange
+			}
+
+			return nil
+		})
+		if err != nil && err != errStopRange {
+			return err
+		}
 
-		return b.Put([]byte(card), data)
+		return nil
 	})
 }
 
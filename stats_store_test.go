@@ -1,12 +1,15 @@
 package leaf
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
 )
 
 func TestStatsDB(t *testing.T) {
@@ -14,21 +17,21 @@ func TestStatsDB(t *testing.T) {
 	require.NoError(t, err)
 	defer os.Remove(tmpfile.Name())
 
-	db, err := OpenBoltStore(tmpfile.Name())
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
 	require.NoError(t, err)
 
-	s1 := Stats{&Supermemo2PlusCustom{Supermemo2Plus{Difficulty: 1}}}
+	s1 := Stats{&Supermemo2PlusCustom{Supermemo2Plus{Difficulty: 1, clock: SystemClock, params: DefaultSupermemo2PlusCustomParams()}}}
 	require.NoError(t, db.SaveStats("deck1", "foo", &s1))
 
-	s2 := Stats{&Supermemo2PlusCustom{Supermemo2Plus{Difficulty: 2}}}
+	s2 := Stats{&Supermemo2PlusCustom{Supermemo2Plus{Difficulty: 2, clock: SystemClock, params: DefaultSupermemo2PlusCustomParams()}}}
 	require.NoError(t, db.SaveStats("deck1", "bar", &s2))
 
-	s3 := Stats{&Supermemo2PlusCustom{Supermemo2Plus{Difficulty: 3}}}
+	s3 := Stats{&Supermemo2PlusCustom{Supermemo2Plus{Difficulty: 3, clock: SystemClock, params: DefaultSupermemo2PlusCustomParams()}}}
 	require.NoError(t, db.SaveStats("deck2", "foo", &s3))
 
 	cards := []string{}
 	stats := []Stats{}
-	err = db.RangeStats("deck1", SRSSupermemo2PlusCustom, func(card string, s *Stats) bool {
+	err = db.RangeStats("deck1", SRSSupermemo2PlusCustom, SystemClock, nil, func(card string, s *Stats) bool {
 		cards = append(cards, card)
 		stats = append(stats, *s)
 		return true
@@ -38,3 +41,241 @@ func TestStatsDB(t *testing.T) {
 	assert.Equal(t, []string{"bar", "foo"}, cards)
 	assert.Equal(t, []Stats{s2, s1}, stats)
 }
+
+func TestStatsDBRecords(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	store, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	sm2 := &Supermemo2{Interval: 6, Easiness: 2.5, Total: 2, Historical: []IntervalSnapshot{{0, 1, 2.5}}}
+	require.NoError(t, store.SaveStats("deck", "foo", &Stats{sm2}))
+
+	t.Run("SaveStats", func(t *testing.T) {
+		err := store.(*boltStore).bolt.View(func(tx *bolt.Tx) error {
+			record := new(statsRecord)
+			require.NoError(t, json.Unmarshal(tx.Bucket([]byte("deck")).Get([]byte("foo")), record))
+			assert.Equal(t, statsSchemaVersion, record.Version)
+			assert.Equal(t, SRSSupermemo2, record.Algorithm)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("legacy records", func(t *testing.T) {
+		err := store.(*boltStore).bolt.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("deck")).Put([]byte("bar"), []byte(`{"Interval":1,"Easiness":2.5}`))
+		})
+		require.NoError(t, err)
+
+		srs := make(map[string]SRS)
+		err = store.RangeRecords("deck", SRSSupermemo2, SystemClock, nil, func(card string, s SRS, stats *Stats) bool {
+			srs[card] = s
+			_, ok := stats.SRSAlgorithm.(*Supermemo2)
+			assert.True(t, ok)
+			return true
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]SRS{"foo": SRSSupermemo2, "bar": ""}, srs)
+
+		record := func() *statsRecord {
+			record := new(statsRecord)
+			err := store.(*boltStore).bolt.View(func(tx *bolt.Tx) error {
+				return json.Unmarshal(tx.Bucket([]byte("deck")).Get([]byte("bar")), record)
+			})
+			require.NoError(t, err)
+			return record
+		}
+		// listing doesn't modify records
+		assert.Equal(t, 0, record().Version)
+
+		require.NoError(t, store.RangeStats("deck", SRSSupermemo2, SystemClock, nil, func(card string, s *Stats) bool { return true }))
+		assert.Equal(t, statsSchemaVersion, record().Version)
+		assert.Equal(t, SRSSupermemo2, record().Algorithm)
+	})
+
+	t.Run("mismatch refuse", func(t *testing.T) {
+		require.NoError(t, store.SaveStats("deck", "baz", &Stats{NewFSRS(SystemClock, DefaultFSRSParams())}))
+		defer store.DeleteStats("deck", "baz")
+
+		cards := []string{}
+		err := store.RangeStats("deck", SRSFSRS, SystemClock, nil, func(card string, s *Stats) bool {
+			cards = append(cards, card)
+			return true
+		})
+		assert.Equal(t, []string{"baz"}, cards)
+
+		mErr, ok := err.(*MismatchError)
+		require.True(t, ok)
+		assert.Equal(t, map[string]SRS{"foo": SRSSupermemo2, "bar": SRSSupermemo2}, mErr.Cards)
+		assert.Equal(t, "deck deck uses fsrs, stats for 2 cards were saved with a different algorithm: bar (sm2), foo (sm2)", err.Error())
+	})
+
+	t.Run("mismatch migrate", func(t *testing.T) {
+		store.(*boltStore).policy = MismatchMigrate
+
+		stats := make(map[string]*Stats)
+		err := store.RangeStats("deck", SRSFSRS, SystemClock, nil, func(card string, s *Stats) bool {
+			stats[card] = s
+			return true
+		})
+		require.NoError(t, err)
+		require.Len(t, stats, 2)
+
+		fs := stats["foo"].SRSAlgorithm.(*FSRS)
+		assert.InDelta(t, 6, fs.Stability, 0.01)
+
+		store.(*boltStore).policy = MismatchRefuse
+		err = store.RangeStats("deck", SRSFSRS, SystemClock, nil, func(card string, s *Stats) bool { return true })
+		require.NoError(t, err)
+	})
+
+	t.Run("unknown algorithm", func(t *testing.T) {
+		err := store.(*boltStore).bolt.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("deck")).Put([]byte("qux"), []byte(`{"v":1,"srs":"custom","stats":{}}`))
+		})
+		require.NoError(t, err)
+
+		srs := make(map[string]SRS)
+		err = store.RangeRecords("deck", SRSFSRS, SystemClock, nil, func(card string, s SRS, stats *Stats) bool {
+			srs[card] = s
+			assert.Equal(t, card == "qux", stats == nil)
+			return true
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]SRS{"foo": SRSFSRS, "bar": SRSFSRS, "qux": "custom"}, srs)
+
+		store.(*boltStore).policy = MismatchMigrate
+		defer func() { store.(*boltStore).policy = MismatchRefuse }()
+
+		cards := []string{}
+		err = store.RangeStats("deck", SRSFSRS, SystemClock, nil, func(card string, s *Stats) bool {
+			cards = append(cards, card)
+			return true
+		})
+		assert.Equal(t, []string{"bar", "foo"}, cards)
+
+		mErr, ok := err.(*MismatchError)
+		require.True(t, ok)
+		assert.Equal(t, map[string]SRS{"qux": "custom"}, mErr.Cards)
+	})
+}
+
+func TestReviewLog(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	r1 := Review{Deck: "deck1", Card: "foo", Score: ReviewScoreAgain, Answer: "bar", Elapsed: time.Second}
+	require.NoError(t, db.LogReview(&r1))
+
+	r2 := Review{Deck: "deck1", Card: "foo", Score: ReviewScoreEasy, Rating: 1, PrevInterval: 0.2, Interval: 0.37}
+	require.NoError(t, db.LogReview(&r2))
+
+	r3 := Review{Deck: "deck2", Card: "foo", Score: ReviewScoreGood, Rating: 0.6}
+	require.NoError(t, db.LogReview(&r3))
+
+	reviews := []Review{}
+	err = db.RangeReviews("deck1", func(review *Review) bool {
+		reviews = append(reviews, *review)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []Review{r1, r2}, reviews)
+
+	reviews = []Review{}
+	err = db.RangeReviews("deck3", func(review *Review) bool {
+		reviews = append(reviews, *review)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Empty(t, reviews)
+}
+
+func TestRenameCards(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	for idx, card := range []string{"foo", "bar", "2"} {
+		s := &Stats{NewSupermemo2(clock, DefaultSupermemo2Params())}
+		s.SRSAlgorithm.(*Supermemo2).Total = idx
+		require.NoError(t, db.SaveStats("deck1", card, s))
+	}
+	require.NoError(t, db.LogReview(&Review{Deck: "deck1", Card: "foo", Score: ReviewScoreEasy, Rating: 1}))
+	require.NoError(t, db.LogReview(&Review{Deck: "deck1", Card: "baz", Score: ReviewScoreEasy, Rating: 1}))
+
+	require.NoError(t, db.RenameCards("deck1", map[string]string{"foo": "1", "bar": "2", "missing": "3"}))
+	require.NoError(t, db.DeleteStats("deck1", "missing"))
+	require.NoError(t, db.DeleteStats("deck2", "foo"))
+
+	decks, err := db.Decks()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deck1"}, decks)
+	require.NoError(t, db.RenameCards("deck2", map[string]string{"foo": "1"}))
+
+	totals := make(map[string]int)
+	err = db.RangeStats("deck1", SRSSupermemo2, clock, nil, func(card string, s *Stats) bool {
+		totals[card] = s.SRSAlgorithm.(*Supermemo2).Total
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"1": 0, "bar": 1, "2": 2}, totals)
+
+	cards := []string{}
+	err = db.RangeReviews("deck1", func(review *Review) bool {
+		cards = append(cards, review.Card)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "baz"}, cards)
+
+	require.NoError(t, db.DeleteStats("deck1", "bar"))
+	delete(totals, "bar")
+	err = db.RangeStats("deck1", SRSSupermemo2, clock, nil, func(card string, s *Stats) bool {
+		assert.Contains(t, totals, card)
+		delete(totals, card)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Empty(t, totals)
+}
+
+func TestRenameLegacyCards(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "leaf.db")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+
+	db, err := OpenBoltStore(tmpfile.Name(), MismatchRefuse)
+	require.NoError(t, err)
+	defer db.Close()
+
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	require.NoError(t, db.SaveStats("deck", "foo", &Stats{NewSupermemo2(clock, DefaultSupermemo2Params())}))
+	require.NoError(t, db.RenameLegacyCards("deck", map[string]string{"foo": "1"}))
+
+	// records are moved only once for a deck
+	require.NoError(t, db.SaveStats("deck", "bar", &Stats{NewSupermemo2(clock, DefaultSupermemo2Params())}))
+	require.NoError(t, db.RenameLegacyCards("deck", map[string]string{"bar": "2"}))
+
+	cards := []string{}
+	err = db.RangeRecords("deck", SRSSupermemo2, clock, nil, func(card string, srs SRS, s *Stats) bool {
+		cards = append(cards, card)
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "bar"}, cards)
+
+	decks, err := db.Decks()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deck"}, decks)
+}
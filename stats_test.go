@@ -8,14 +8,17 @@ import (
 )
 
 func TestIsReady(t *testing.T) {
-	s := &Stats{&Supermemo2Plus{LastReviewedAt: time.Now(), Interval: 1}}
-	assert.False(t, s.IsReady())
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	s := &Stats{&Supermemo2Plus{LastReviewedAt: clock.Now(), Interval: 1}}
+	assert.False(t, s.IsReady(clock))
 
-	s = &Stats{&Supermemo2Plus{LastReviewedAt: time.Now().Add(-24 * time.Hour), Interval: 1}}
-	assert.True(t, s.IsReady())
+	s = &Stats{&Supermemo2Plus{LastReviewedAt: clock.Now().Add(-25 * time.Hour), Interval: 1}}
+	assert.True(t, s.IsReady(clock))
 
-	s = NewStats(SRSSupermemo2Plus)
-	assert.True(t, s.IsReady())
+	s = &Stats{NewSupermemo2Plus(clock, DefaultSupermemo2PlusParams())}
+	assert.True(t, s.IsReady(clock))
 	s.Advance(5)
-	assert.False(t, s.IsReady())
+	assert.False(t, s.IsReady(clock))
+	clock.Advance(24 * time.Hour)
+	assert.True(t, s.IsReady(clock))
 }
@@ -2,6 +2,7 @@ package leaf
 
 import (
 	"encoding/json"
+	"fmt"
 	"math"
 	"time"
 )
@@ -14,16 +15,53 @@ type Supermemo2 struct {
 	Correct        int
 	Total          int
 	Historical     []IntervalSnapshot
+
+	clock  Clock
+	params Supermemo2Params
+}
+
+// Supermemo2Params defines tunable parameters of Supermemo2.
+type Supermemo2Params struct {
+	// InitialEase is an easiness of new cards, INITIAL_EASE property.
+	InitialEase float64
+	// MinEase is a lower bound of easiness, MIN_EASE property.
+	MinEase float64
+	// MaxInterval is an upper bound of intervals in days,
+	// MAX_INTERVAL property.
+	MaxInterval float64
+}
+
+// DefaultSupermemo2Params returns parameters of the original SM2 algorithm.
+func DefaultSupermemo2Params() Supermemo2Params {
+	return Supermemo2Params{InitialEase: 2.5, MinEase: 1.3, MaxInterval: math.Inf(1)}
+}
+
+// ParseSupermemo2Params reads Supermemo2Params from deck parameters,
+// defaults are used for missing values.
+func ParseSupermemo2Params(params SRSParams) (Supermemo2Params, error) {
+	res := DefaultSupermemo2Params()
+	p := &paramsParser{params: params}
+	p.float("INITIAL_EASE", &res.InitialEase, 1, 10)
+	p.float("MIN_EASE", &res.MinEase, 1, 10)
+	p.float("MAX_INTERVAL", &res.MaxInterval, 1, math.Inf(1))
+	if p.err == nil && res.MinEase > res.InitialEase {
+		p.err = fmt.Errorf("params: MIN_EASE should not exceed INITIAL_EASE")
+	}
+
+	return res, p.err
 }
 
-// NewSupermemo2 returns a new Supermemo2 instance
-func NewSupermemo2() *Supermemo2 {
+// NewSupermemo2 returns a new Supermemo2 instance that uses provided
+// clock and parameters.
+func NewSupermemo2(clock Clock, params Supermemo2Params) *Supermemo2 {
 	return &Supermemo2{
-		LastReviewedAt: time.Now(),
+		LastReviewedAt: timeNow(clock),
 		Interval:       0,
-		Easiness:       2.5,
+		Easiness:       params.InitialEase,
 		Correct:        0,
 		Total:          0,
+		clock:          clock,
+		params:         params,
 	}
 }
 
@@ -39,11 +77,12 @@ func (sm *Supermemo2) Less(other SRSAlgorithm) bool {
 
 // Advance advances supermemo state for a card.
 func (sm *Supermemo2) Advance(rating float64) float64 {
+	now := timeNow(sm.clock)
 	sm.Total++
-	sm.LastReviewedAt = time.Now()
+	sm.LastReviewedAt = now
 
 	sm.Easiness += 0.1 - (1-rating)*(0.4+(1-rating)*0.5)
-	sm.Easiness = math.Max(sm.Easiness, 1.3)
+	sm.Easiness = math.Max(sm.Easiness, sm.params.MinEase)
 
 	interval := 1.0
 	if rating >= ratingSuccess {
@@ -62,11 +101,11 @@ func (sm *Supermemo2) Advance(rating float64) float64 {
 	}
 	sm.Historical = append(
 		sm.Historical,
-		IntervalSnapshot{time.Now().Unix(), sm.Interval, sm.Easiness},
+		IntervalSnapshot{now.Unix(), sm.Interval, sm.Easiness},
 	)
 
-	sm.Interval = interval
-	return interval
+	sm.Interval = math.Min(interval, sm.params.MaxInterval)
+	return sm.Interval
 }
 
 // MarshalJSON implements json.Marshaller for Supermemo2
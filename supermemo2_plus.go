@@ -19,15 +19,65 @@ type Supermemo2Plus struct {
 	Difficulty     float64
 	Interval       float64
 	Historical     []IntervalSnapshot
+
+	clock  Clock
+	params Supermemo2PlusParams
+}
+
+// Supermemo2PlusParams defines tunable parameters of SM2+ curves.
+type Supermemo2PlusParams struct {
+	// InitialDifficulty is a difficulty of new cards within [0, 1]
+	// range, INITIAL_DIFFICULTY property.
+	InitialDifficulty float64
+	// DifficultyRate is an inverse speed of difficulty changes,
+	// DIFFICULTY_RATE property.
+	DifficultyRate float64
+	// DifficultyWeight defines interval growth for the easiest
+	// cards, DIFFICULTY_WEIGHT property.
+	DifficultyWeight float64
+	// MinInterval is a lower bound of intervals in days after
+	// successful reviews, MIN_INTERVAL property. Only used by
+	// Supermemo2PlusCustom.
+	MinInterval float64
+	// MaxInterval is an upper bound of intervals in days,
+	// MAX_INTERVAL property.
+	MaxInterval float64
+}
+
+// DefaultSupermemo2PlusParams returns parameters of the original SM2+ algorithm.
+func DefaultSupermemo2PlusParams() Supermemo2PlusParams {
+	return Supermemo2PlusParams{0.3, 17, 3, 0, math.Inf(1)}
+}
+
+// minDifficultyWeight keeps weight of the hardest cards at 1 or
+// above, lower weights grow intervals of failed reviews and shrink
+// intervals of successful ones.
+const minDifficultyWeight = 2.7
+
+// ParseSupermemo2PlusParams reads Supermemo2PlusParams from deck
+// parameters, provided defaults are used for missing values.
+func ParseSupermemo2PlusParams(params SRSParams, defaults Supermemo2PlusParams) (Supermemo2PlusParams, error) {
+	res := defaults
+	p := &paramsParser{params: params}
+	p.float("INITIAL_DIFFICULTY", &res.InitialDifficulty, 0, 1)
+	p.float("DIFFICULTY_RATE", &res.DifficultyRate, 1, 1000)
+	p.float("DIFFICULTY_WEIGHT", &res.DifficultyWeight, minDifficultyWeight, 10)
+	p.float("MIN_INTERVAL", &res.MinInterval, 0, 1)
+	p.float("MAX_INTERVAL", &res.MaxInterval, 1, math.Inf(1))
+
+	return res, p.err
 }
 
-// NewSupermemo2Plus returns a new Supermemo2Plus instance
-func NewSupermemo2Plus() *Supermemo2Plus {
+// NewSupermemo2Plus returns a new Supermemo2Plus instance that uses
+// provided clock and parameters.
+func NewSupermemo2Plus(clock Clock, params Supermemo2PlusParams) *Supermemo2Plus {
 	return &Supermemo2Plus{
-		LastReviewedAt: time.Now().Add(-4 * time.Hour),
-		Difficulty:     0.3,
+		LastReviewedAt: timeNow(clock).Add(-4 * time.Hour),
+		Difficulty:     params.InitialDifficulty,
 		Interval:       0.2,
 		Historical:     make([]IntervalSnapshot, 0),
+		clock:          clock,
+		params:         params,
 	}
 }
 
@@ -43,7 +93,7 @@ func (sm *Supermemo2Plus) Less(other SRSAlgorithm) bool {
 
 // PercentOverdue returns corresponding SM2+ value for a Card.
 func (sm *Supermemo2Plus) PercentOverdue() float64 {
-	percentOverdue := time.Since(sm.LastReviewedAt).Hours() / float64(24*sm.Interval)
+	percentOverdue := timeNow(sm.clock).Sub(sm.LastReviewedAt).Hours() / float64(24*sm.Interval)
 	return math.Min(2, percentOverdue)
 }
 
@@ -55,24 +105,25 @@ func (sm *Supermemo2Plus) Advance(rating float64) float64 {
 		percentOverdue = sm.PercentOverdue()
 	}
 
-	sm.Difficulty += percentOverdue / 17 * (8 - 9*rating)
+	sm.Difficulty += percentOverdue / sm.params.DifficultyRate * (8 - 9*rating)
 	sm.Difficulty = math.Max(0, math.Min(1, sm.Difficulty))
-	difficultyWeight := 3 - 1.7*sm.Difficulty
+	difficultyWeight := sm.params.DifficultyWeight - 1.7*sm.Difficulty
 
 	factor := 1.0 / math.Pow(difficultyWeight, 2)
 	if success {
 		factor = 1.0 + (difficultyWeight-1)*percentOverdue
 	}
 
-	sm.LastReviewedAt = time.Now()
+	now := timeNow(sm.clock)
+	sm.LastReviewedAt = now
 	if sm.Historical == nil {
 		sm.Historical = make([]IntervalSnapshot, 0)
 	}
 	sm.Historical = append(
 		sm.Historical,
-		IntervalSnapshot{time.Now().Unix(), sm.Interval, sm.Difficulty},
+		IntervalSnapshot{now.Unix(), sm.Interval, sm.Difficulty},
 	)
-	sm.Interval = sm.Interval * factor
+	sm.Interval = math.Min(sm.Interval*factor, sm.params.MaxInterval)
 	return sm.Interval
 }
 
@@ -2,7 +2,6 @@ package leaf
 
 import (
 	"math"
-	"time"
 )
 
 // Supermemo2PlusCustom calculates review intervals using altered SM2+ algorithm
@@ -10,9 +9,15 @@ type Supermemo2PlusCustom struct {
 	Supermemo2Plus
 }
 
+// DefaultSupermemo2PlusCustomParams returns parameters of the custom SM2+ curve.
+func DefaultSupermemo2PlusCustomParams() Supermemo2PlusParams {
+	return Supermemo2PlusParams{0.3, 35, 3.5, 0.2, 300}
+}
+
 // NewSupermemo2PlusCustom returns a new Supermemo2PlusCustom instance
-func NewSupermemo2PlusCustom() *Supermemo2PlusCustom {
-	sm := NewSupermemo2Plus()
+// that uses provided clock and parameters.
+func NewSupermemo2PlusCustom(clock Clock, params Supermemo2PlusParams) *Supermemo2PlusCustom {
+	sm := NewSupermemo2Plus(clock, params)
 	return &Supermemo2PlusCustom{*sm}
 }
 
@@ -29,25 +34,26 @@ func (sm *Supermemo2PlusCustom) Advance(rating float64) float64 {
 		percentOverdue = sm.PercentOverdue()
 	}
 
-	sm.Difficulty += percentOverdue / 35 * (8 - 9*rating)
+	sm.Difficulty += percentOverdue / sm.params.DifficultyRate * (8 - 9*rating)
 	sm.Difficulty = math.Max(0, math.Min(1, sm.Difficulty))
-	difficultyWeight := 3.5 - 1.7*sm.Difficulty
+	difficultyWeight := sm.params.DifficultyWeight - 1.7*sm.Difficulty
 
 	minInterval := math.Min(1.0, sm.Interval)
 	factor := minInterval / math.Pow(difficultyWeight, 2)
 	if success {
-		minInterval = 0.2
+		minInterval = sm.params.MinInterval
 		factor = minInterval + (difficultyWeight-1)*percentOverdue
 	}
 
-	sm.LastReviewedAt = time.Now()
+	now := timeNow(sm.clock)
+	sm.LastReviewedAt = now
 	if sm.Historical == nil {
 		sm.Historical = make([]IntervalSnapshot, 0)
 	}
 	sm.Historical = append(
 		sm.Historical,
-		IntervalSnapshot{time.Now().Unix(), sm.Interval, sm.Difficulty},
+		IntervalSnapshot{now.Unix(), sm.Interval, sm.Difficulty},
 	)
-	sm.Interval = math.Max(minInterval, math.Min(sm.Interval*factor, 300))
+	sm.Interval = math.Max(minInterval, math.Min(sm.Interval*factor, sm.params.MaxInterval))
 	return sm.Interval
 }
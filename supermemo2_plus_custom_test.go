@@ -18,23 +18,26 @@ func TestNextReviewAt(t *testing.T) {
 	sm = &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: time.Unix(100, 0).Add(-24 * time.Hour), Interval: 1}}
 	assert.Equal(t, int64(100), sm.NextReviewAt().Unix())
 
-	sm = NewSupermemo2PlusCustom()
-	assert.InDelta(t, time.Since(sm.NextReviewAt()), time.Duration(0), float64(time.Minute))
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm = NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())
+	assert.Equal(t, clock.Now(), sm.NextReviewAt())
 	interval := sm.Advance(1)
-	assert.InDelta(t, time.Duration(24*interval)*time.Hour, time.Until(sm.NextReviewAt()), float64(time.Minute))
+	assert.Equal(t, clock.Now().Add(time.Duration(24*interval)*time.Hour), sm.NextReviewAt())
 }
 
 func TestPercentOverdue(t *testing.T) {
-	sm := &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: time.Now().Add(-time.Hour), Interval: 1}}
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm := &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: clock.Now().Add(-time.Hour), Interval: 1, clock: clock}}
 	assert.InDelta(t, 0.04, sm.PercentOverdue(), 0.01)
 
-	sm = &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: time.Now().Add(-48 * time.Hour), Interval: 1}}
+	sm = &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: clock.Now().Add(-48 * time.Hour), Interval: 1, clock: clock}}
 	assert.InDelta(t, 2.0, sm.PercentOverdue(), 0.01)
 }
 
 func TestLess(t *testing.T) {
-	sm1 := &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: time.Now().Add(-time.Hour), Interval: 1}}
-	sm2 := &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: time.Now().Add(-48 * time.Hour), Interval: 1}}
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm1 := &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: clock.Now().Add(-time.Hour), Interval: 1, clock: clock}}
+	sm2 := &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: clock.Now().Add(-48 * time.Hour), Interval: 1, clock: clock}}
 
 	slice := []SRSAlgorithm{sm1, sm2}
 	sort.Slice(slice, func(i, j int) bool { return slice[j].Less(slice[i]) })
@@ -49,14 +52,13 @@ func TestRecord(t *testing.T) {
 	}
 	for idx, rating := range []float64{0.5, 0.6, 1.0} {
 		t.Run(fmt.Sprintf("%f", rating), func(t *testing.T) {
-			sm := NewSupermemo2PlusCustom()
+			clock := NewSimulatedClock(time.Unix(100, 0))
+			sm := NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())
 			intervals := []float64{}
 			for i := 0; i < 9; i++ {
 				interval := sm.Advance(rating)
 				intervals = append(intervals, interval)
-
-				curInterval := sm.Interval * 24 * float64(time.Hour)
-				sm.LastReviewedAt = time.Now().Add(-time.Duration(curInterval))
+				clock.Advance(time.Duration(sm.Interval * 24 * float64(time.Hour)))
 			}
 
 			assert.InDeltaSlice(t, results[idx], intervals, 0.01)
@@ -64,14 +66,13 @@ func TestRecord(t *testing.T) {
 	}
 
 	t.Run("sequence", func(t *testing.T) {
-		sm := NewSupermemo2PlusCustom()
+		clock := NewSimulatedClock(time.Unix(100, 0))
+		sm := NewSupermemo2PlusCustom(clock, DefaultSupermemo2PlusCustomParams())
 		intervals := []float64{}
 		for _, rating := range []float64{1, 1, 1, 1, 0.5, 1} {
 			interval := sm.Advance(rating)
 			intervals = append(intervals, interval)
-
-			curInterval := sm.Interval * 24 * float64(time.Hour)
-			sm.LastReviewedAt = time.Now().Add(-time.Duration(curInterval))
+			clock.Advance(time.Duration(sm.Interval * 24 * float64(time.Hour)))
 		}
 
 		assert.InDeltaSlice(t, []float64{0.37, 0.86, 2.00, 4.76, 1, 2.25}, intervals, 0.01)
@@ -85,8 +86,23 @@ func TestRecord(t *testing.T) {
 	})
 }
 
+func TestParams(t *testing.T) {
+	params := DefaultSupermemo2PlusCustomParams()
+	params.MaxInterval = 1
+	params.MinInterval = 0.5
+
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm := NewSupermemo2PlusCustom(clock, params)
+	intervals := []float64{}
+	for _, rating := range []float64{1, 1, 1, 1} {
+		intervals = append(intervals, sm.Advance(rating))
+		clock.Advance(time.Duration(sm.Interval * 24 * float64(time.Hour)))
+	}
+	assert.InDeltaSlice(t, []float64{0.5, 1, 1, 1}, intervals, 0.01)
+}
+
 func TestJsonMarshalling(t *testing.T) {
-	sm := &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: time.Unix(100, 0), Interval: 1, Difficulty: 0.2}}
+	sm := &Supermemo2PlusCustom{Supermemo2Plus{LastReviewedAt: time.Unix(100, 0).UTC(), Interval: 1, Difficulty: 0.2}}
 	res, err := json.Marshal(sm)
 	require.NoError(t, err)
 
@@ -18,23 +18,26 @@ func TestSM2PlusNextReviewAt(t *testing.T) {
 	sm = &Supermemo2Plus{LastReviewedAt: time.Unix(100, 0).Add(-24 * time.Hour), Interval: 1}
 	assert.Equal(t, int64(100), sm.NextReviewAt().Unix())
 
-	sm = NewSupermemo2Plus()
-	assert.InDelta(t, time.Since(sm.NextReviewAt()), time.Duration(0), float64(time.Minute))
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm = NewSupermemo2Plus(clock, DefaultSupermemo2PlusParams())
+	assert.Equal(t, clock.Now(), sm.NextReviewAt())
 	interval := sm.Advance(1)
-	assert.InDelta(t, time.Duration(24*interval)*time.Hour, time.Until(sm.NextReviewAt()), float64(time.Minute))
+	assert.Equal(t, clock.Now().Add(time.Duration(24*interval)*time.Hour), sm.NextReviewAt())
 }
 
 func TestSM2PlusPercentOverdue(t *testing.T) {
-	sm := Supermemo2Plus{LastReviewedAt: time.Now().Add(-time.Hour), Interval: 1}
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm := Supermemo2Plus{LastReviewedAt: clock.Now().Add(-time.Hour), Interval: 1, clock: clock}
 	assert.InDelta(t, 0.04, sm.PercentOverdue(), 0.01)
 
-	sm = Supermemo2Plus{LastReviewedAt: time.Now().Add(-48 * time.Hour), Interval: 1}
+	sm = Supermemo2Plus{LastReviewedAt: clock.Now().Add(-48 * time.Hour), Interval: 1, clock: clock}
 	assert.InDelta(t, 2.0, sm.PercentOverdue(), 0.01)
 }
 
 func TestSM2PlusLess(t *testing.T) {
-	sm1 := &Supermemo2Plus{LastReviewedAt: time.Now().Add(-time.Hour), Interval: 1}
-	sm2 := &Supermemo2Plus{LastReviewedAt: time.Now().Add(-48 * time.Hour), Interval: 1}
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm1 := &Supermemo2Plus{LastReviewedAt: clock.Now().Add(-time.Hour), Interval: 1, clock: clock}
+	sm2 := &Supermemo2Plus{LastReviewedAt: clock.Now().Add(-48 * time.Hour), Interval: 1, clock: clock}
 
 	slice := []SRSAlgorithm{sm1, sm2}
 	sort.Slice(slice, func(i, j int) bool { return slice[j].Less(slice[i]) })
@@ -49,14 +52,13 @@ func TestSM2PlusRecord(t *testing.T) {
 	}
 	for idx, rating := range []float64{0.5, 0.6, 1.0} {
 		t.Run(fmt.Sprintf("%f", rating), func(t *testing.T) {
-			sm := NewSupermemo2Plus()
+			clock := NewSimulatedClock(time.Unix(100, 0))
+			sm := NewSupermemo2Plus(clock, DefaultSupermemo2PlusParams())
 			intervals := []float64{}
 			for i := 0; i < 9; i++ {
 				interval := sm.Advance(rating)
 				intervals = append(intervals, interval)
-
-				curInterval := sm.Interval * 24 * float64(time.Hour)
-				sm.LastReviewedAt = time.Now().Add(-time.Duration(curInterval))
+				clock.Advance(time.Duration(sm.Interval * 24 * float64(time.Hour)))
 			}
 
 			assert.InDeltaSlice(t, results[idx], intervals, 0.01)
@@ -64,14 +66,13 @@ func TestSM2PlusRecord(t *testing.T) {
 	}
 
 	t.Run("sequence", func(t *testing.T) {
-		sm := NewSupermemo2Plus()
+		clock := NewSimulatedClock(time.Unix(100, 0))
+		sm := NewSupermemo2Plus(clock, DefaultSupermemo2PlusParams())
 		intervals := []float64{}
 		for _, rating := range []float64{1, 1, 1, 1, 0.5, 1} {
 			interval := sm.Advance(rating)
 			intervals = append(intervals, interval)
-
-			curInterval := sm.Interval * 24 * float64(time.Hour)
-			sm.LastReviewedAt = time.Now().Add(-time.Duration(curInterval))
+			clock.Advance(time.Duration(sm.Interval * 24 * float64(time.Hour)))
 		}
 
 		assert.InDeltaSlice(t, []float64{0.46, 1.23, 3.42, 9.84, 1.54, 4.05}, intervals, 0.01)
@@ -85,8 +86,34 @@ func TestSM2PlusRecord(t *testing.T) {
 	})
 }
 
+func TestSM2PlusMinDifficultyWeight(t *testing.T) {
+	params := DefaultSupermemo2PlusParams()
+	params.DifficultyWeight = minDifficultyWeight
+	_, err := ParseSupermemo2PlusParams(SRSParams{"DIFFICULTY_WEIGHT": "2.7"}, params)
+	require.NoError(t, err)
+	_, err = ParseSupermemo2PlusParams(SRSParams{"DIFFICULTY_WEIGHT": "2.6"}, params)
+	assert.Error(t, err)
+
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm := NewSupermemo2Plus(clock, params)
+	interval := sm.Advance(1)
+	clock.Advance(time.Duration(interval * 24 * float64(time.Hour)))
+
+	// failures push difficulty to 1 and never grow intervals
+	for i := 0; i < 5; i++ {
+		next := sm.Advance(0)
+		assert.True(t, next <= interval, "failure grows interval %f to %f", interval, next)
+		interval = next
+	}
+	assert.Equal(t, 1.0, sm.Difficulty)
+
+	clock.Advance(time.Duration(interval * 24 * float64(time.Hour)))
+	next := sm.Advance(0.6)
+	assert.True(t, next >= interval, "success shrinks interval %f to %f", interval, next)
+}
+
 func TestSM2PlusJsonMarshalling(t *testing.T) {
-	sm := &Supermemo2Plus{LastReviewedAt: time.Unix(100, 0), Interval: 1, Difficulty: 0.2}
+	sm := &Supermemo2Plus{LastReviewedAt: time.Unix(100, 0).UTC(), Interval: 1, Difficulty: 0.2}
 	res, err := json.Marshal(sm)
 	require.NoError(t, err)
 
@@ -18,10 +18,11 @@ func TestSM2NextReviewAt(t *testing.T) {
 	sm = &Supermemo2{LastReviewedAt: time.Unix(100, 0).Add(-24 * time.Hour), Interval: 1}
 	assert.Equal(t, int64(100), sm.NextReviewAt().Unix())
 
-	sm = NewSupermemo2()
-	assert.InDelta(t, time.Since(sm.NextReviewAt()), time.Duration(0), float64(time.Minute))
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm = NewSupermemo2(clock, DefaultSupermemo2Params())
+	assert.Equal(t, clock.Now(), sm.NextReviewAt())
 	interval := sm.Advance(1)
-	assert.InDelta(t, time.Duration(24*interval)*time.Hour, time.Until(sm.NextReviewAt()), float64(time.Minute))
+	assert.Equal(t, clock.Now().Add(time.Duration(24*interval)*time.Hour), sm.NextReviewAt())
 }
 
 func TestSM2Less(t *testing.T) {
@@ -41,14 +42,13 @@ func TestSM2Record(t *testing.T) {
 	}
 	for idx, rating := range []float64{0.5, 0.6, 1.0} {
 		t.Run(fmt.Sprintf("%f", rating), func(t *testing.T) {
-			sm := NewSupermemo2()
+			clock := NewSimulatedClock(time.Unix(100, 0))
+			sm := NewSupermemo2(clock, DefaultSupermemo2Params())
 			intervals := []float64{}
 			for i := 0; i < 9; i++ {
 				interval := sm.Advance(rating)
 				intervals = append(intervals, interval)
-
-				curInterval := sm.Interval * 24 * float64(time.Hour)
-				sm.LastReviewedAt = time.Now().Add(-time.Duration(curInterval))
+				clock.Advance(time.Duration(sm.Interval * 24 * float64(time.Hour)))
 			}
 
 			assert.InDeltaSlice(t, results[idx], intervals, 0.01)
@@ -56,30 +56,44 @@ func TestSM2Record(t *testing.T) {
 	}
 
 	t.Run("sequence", func(t *testing.T) {
-		sm := NewSupermemo2()
+		clock := NewSimulatedClock(time.Unix(100, 0))
+		sm := NewSupermemo2(clock, DefaultSupermemo2Params())
 		intervals := []float64{}
 		for _, rating := range []float64{1, 1, 1, 1, 0.5, 1} {
 			interval := sm.Advance(rating)
 			intervals = append(intervals, interval)
-
-			curInterval := sm.Interval * 24 * float64(time.Hour)
-			sm.LastReviewedAt = time.Now().Add(-time.Duration(curInterval))
+			clock.Advance(time.Duration(sm.Interval * 24 * float64(time.Hour)))
 		}
 
 		assert.InDeltaSlice(t, []float64{1.0, 6.0, 17.0, 49.0, 1.0, 3.0}, intervals, 0.01)
 
 		historical := []float64{}
+		timestamps := []int64{}
 		for _, snap := range sm.Historical {
-			assert.NotNil(t, snap.Timestamp)
 			historical = append(historical, snap.Interval)
+			timestamps = append(timestamps, snap.Timestamp)
 		}
 
 		assert.InDeltaSlice(t, []float64{0.0, 1.0, 6.0, 17.0, 49.0, 1.0}, historical, 0.01)
+		assert.Equal(t, []int64{100, 86500, 604900, 2073700, 6307300, 6393700}, timestamps)
 	})
 }
 
+func TestSM2Params(t *testing.T) {
+	clock := NewSimulatedClock(time.Unix(100, 0))
+	sm := NewSupermemo2(clock, Supermemo2Params{InitialEase: 2, MinEase: 1.3, MaxInterval: 10})
+	assert.Equal(t, 2.0, sm.Easiness)
+
+	intervals := []float64{}
+	for _, rating := range []float64{1, 1, 1, 1} {
+		intervals = append(intervals, sm.Advance(rating))
+		clock.Advance(time.Duration(sm.Interval * 24 * float64(time.Hour)))
+	}
+	assert.InDeltaSlice(t, []float64{1, 6, 10, 10}, intervals, 0.01)
+}
+
 func TestSM2JsonMarshalling(t *testing.T) {
-	sm := &Supermemo2{LastReviewedAt: time.Unix(100, 0), Interval: 1, Easiness: 2.5}
+	sm := &Supermemo2{LastReviewedAt: time.Unix(100, 0).UTC(), Interval: 1, Easiness: 2.5}
 	res, err := json.Marshal(sm)
 	require.NoError(t, err)
 
@@ -0,0 +1,112 @@
+package leaf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TagFilter selects cards using headline tags. Matched cards have all
+// included tags and none of excluded ones, empty filter matches all
+// cards.
+type TagFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// ParseTagFilter parses a tag filter expression similar to org-mode
+// tag matches, e.g. "+verbs -irregular" or "+verbs-irregular". Tags
+// without a sign are included.
+func ParseTagFilter(expr string) (TagFilter, error) {
+	filter := TagFilter{}
+	sign, tag := '+', make([]rune, 0)
+	flush := func() {
+		if len(tag) == 0 {
+			return
+		}
+
+		if sign == '-' {
+			filter.Exclude = append(filter.Exclude, string(tag))
+		} else {
+			filter.Include = append(filter.Include, string(tag))
+		}
+		sign, tag = '+', tag[:0]
+	}
+
+	for _, r := range expr {
+		switch {
+		case r == '+' || r == '-':
+			flush()
+			sign = r
+		case unicode.IsSpace(r):
+			flush()
+		case isTagRune(r):
+			tag = append(tag, r)
+		default:
+			return filter, fmt.Errorf("tags: invalid filter %q", expr)
+		}
+	}
+	flush()
+
+	return filter, nil
+}
+
+// Match reports whether provided tags satisfy the filter.
+func (f TagFilter) Match(tags []string) bool {
+	for _, tag := range f.Include {
+		if !hasTag(tags, tag) {
+			return false
+		}
+	}
+
+	for _, tag := range f.Exclude {
+		if hasTag(tags, tag) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String returns filter expression.
+func (f TagFilter) String() string {
+	parts := make([]string, 0, len(f.Include)+len(f.Exclude))
+	for _, tag := range f.Include {
+		parts = append(parts, "+"+tag)
+	}
+	for _, tag := range f.Exclude {
+		parts = append(parts, "-"+tag)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// mergeTags returns inherited tags followed by own tags that are not
+// inherited.
+func mergeTags(inherited, own []string) []string {
+	var result []string
+	result = append(result, inherited...)
+	for _, tag := range own {
+		if !hasTag(result, tag) {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTagRune reports whether a rune can be used in a headline tag,
+// only ASCII tags are parsed in headlines.
+func isTagRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_@#%", r)
+}
@@ -0,0 +1,49 @@
+package leaf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTagFilter(t *testing.T) {
+	tcs := []struct {
+		expr   string
+		filter TagFilter
+	}{
+		{"", TagFilter{}},
+		{"verbs", TagFilter{Include: []string{"verbs"}}},
+		{"+verbs -irregular", TagFilter{Include: []string{"verbs"}, Exclude: []string{"irregular"}}},
+		{"+verbs-irregular+n5", TagFilter{Include: []string{"verbs", "n5"}, Exclude: []string{"irregular"}}},
+		{"  -a_b  @c ", TagFilter{Include: []string{"@c"}, Exclude: []string{"a_b"}}},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.expr, func(t *testing.T) {
+			filter, err := ParseTagFilter(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.filter, filter)
+		})
+	}
+
+	_, err := ParseTagFilter("+verbs|nouns")
+	assert.EqualError(t, err, `tags: invalid filter "+verbs|nouns"`)
+
+	// non-ASCII tags are not parsed in headlines and can't match
+	_, err = ParseTagFilter("+動詞")
+	assert.EqualError(t, err, `tags: invalid filter "+動詞"`)
+}
+
+func TestTagFilterMatch(t *testing.T) {
+	filter, err := ParseTagFilter("+verbs -irregular")
+	require.NoError(t, err)
+	assert.Equal(t, "+verbs -irregular", filter.String())
+
+	assert.True(t, filter.Match([]string{"verbs"}))
+	assert.True(t, filter.Match([]string{"n5", "Verbs"}))
+	assert.False(t, filter.Match([]string{"verbs", "irregular"}))
+	assert.False(t, filter.Match([]string{"nouns"}))
+	assert.False(t, filter.Match(nil))
+	assert.True(t, TagFilter{}.Match(nil))
+}
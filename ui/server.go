@@ -63,7 +63,7 @@ func (srv *Server) startSession(w http.ResponseWriter, req *http.Request) {
 	}
 
 	deckName := strings.Replace(req.URL.Path, "/start/", "", -1)
-	session, err := srv.dm.ReviewSession(deckName)
+	session, err := srv.dm.ReviewSession(deckName, req.URL.Query().Get("tags"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
@@ -133,8 +133,19 @@ func (srv *Server) resolveAnswer(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	answer := srv.sessionState.ResolveAnswer()
-	res := map[string]string{"answer": answer}
+	var res interface{}
+	if sides, ok := req.URL.Query()["side"]; ok {
+		result := srv.sessionState.ResolveSides(sides)
+		res = map[string]interface{}{
+			"answer":  strings.Join(result.Sides, " "),
+			"sides":   result.Sides,
+			"correct": result.Correct,
+		}
+	} else {
+		answer := srv.sessionState.ResolveAnswer(req.URL.Query().Get("answer"))
+		res = map[string]string{"answer": answer}
+	}
+
 	if err := json.NewEncoder(w).Encode(res); err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 	}
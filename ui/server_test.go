@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -19,10 +20,20 @@ func TestWebUI(t *testing.T) {
 	require.NoError(t, err)
 	defer os.Remove(tmpfile.Name())
 
-	db, err := leaf.OpenBoltStore(tmpfile.Name())
+	db, err := leaf.OpenBoltStore(tmpfile.Name(), leaf.MismatchRefuse)
 	require.NoError(t, err)
 
-	dm, err := leaf.NewDeckManager("../fixtures", db, leaf.OutputFormatOrg)
+	// deck files are updated with card IDs during reviews
+	dir, err := ioutil.TempDir("", "decks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"hiragana.org", "org-mode.org"} {
+		data, err := ioutil.ReadFile(filepath.Join("../fixtures", name))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), data, 0644))
+	}
+
+	dm, err := leaf.NewDeckManager(leaf.DeckSource{Roots: []string{dir}}, db, leaf.OutputFormatOrg, leaf.SystemClock)
 	require.NoError(t, err)
 
 	srv := NewServer(dm)
@@ -54,8 +65,17 @@ func TestWebUI(t *testing.T) {
 		assert.Equal(t, "/emphasis/", stats[0]["card"])
 	})
 
+	t.Run("startReviewInvalidTags", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "http://example.com/start/Hiragana?tags=%2Bfoo%3Dbar", nil)
+		w := httptest.NewRecorder()
+
+		srv.startSession(w, req)
+		res := w.Result()
+		assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	})
+
 	t.Run("startReview", func(t *testing.T) {
-		req := httptest.NewRequest("POST", "http://example.com/start/Hiragana", nil)
+		req := httptest.NewRequest("POST", "http://example.com/start/Hiragana?tags=-foo", nil)
 		w := httptest.NewRecorder()
 
 		srv.startSession(w, req)
@@ -83,7 +103,7 @@ func TestWebUI(t *testing.T) {
 	})
 
 	t.Run("resolveAnswer", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "http://example.com/resolve", nil)
+		req := httptest.NewRequest("GET", "http://example.com/resolve?answer=i", nil)
 		w := httptest.NewRecorder()
 
 		srv.resolveAnswer(w, req)
@@ -94,4 +114,23 @@ func TestWebUI(t *testing.T) {
 		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
 		assert.Equal(t, "i", result["answer"])
 	})
+
+	t.Run("resolveSides", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://example.com/resolve?side=i", nil)
+		w := httptest.NewRecorder()
+
+		srv.resolveAnswer(w, req)
+		res := w.Result()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		result := struct {
+			Answer  string   `json:"answer"`
+			Sides   []string `json:"sides"`
+			Correct []bool   `json:"correct"`
+		}{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&result))
+		assert.Equal(t, "i", result.Answer)
+		assert.Equal(t, []string{"i"}, result.Sides)
+		assert.Equal(t, []bool{true}, result.Correct)
+	})
 }
@@ -6,14 +6,23 @@ import (
 
 // SessionState state holds public state of the ReviewSession.
 type SessionState struct {
-	Total      int             `json:"total"`
-	Left       int             `json:"left"`
-	Question   string          `json:"question"`
-	AnswerLen  int             `json:"answer_length"`
-	RatingType leaf.RatingType `json:"rating_type"`
+	Total       int             `json:"total"`
+	Left        int             `json:"left"`
+	Question    string          `json:"question"`
+	AnswerLen   int             `json:"answer_length"`
+	SideLengths []int           `json:"side_lengths"`
+	RatingType  leaf.RatingType `json:"rating_type"`
 
 	session *leaf.ReviewSession
 	rater   leaf.Rater
+	results []bool
+}
+
+// SidesResult holds correct answers and correctness of each side of
+// a resolved card.
+type SidesResult struct {
+	Sides   []string `json:"sides"`
+	Correct []bool   `json:"correct"`
 }
 
 // NewSessionState constructs a new SessionState.
@@ -26,34 +35,61 @@ func NewSessionState(session *leaf.ReviewSession) *SessionState {
 	}
 
 	s := &SessionState{
-		Total:      session.Total(),
-		Left:       session.Left(),
-		Question:   session.Next(),
-		AnswerLen:  len([]rune(session.CorrectAnswer())),
-		RatingType: session.RatingType(),
-		session:    session,
-		rater:      rater,
+		Total:       session.Total(),
+		Left:        session.Left(),
+		Question:    session.Next(),
+		AnswerLen:   len([]rune(session.CorrectAnswer())),
+		SideLengths: sideLengths(session.CorrectSides()),
+		RatingType:  session.RatingType(),
+		session:     session,
+		rater:       rater,
 	}
 
 	return s
 }
 
 // ResolveAnswer submits answer to a session.
-func (s *SessionState) ResolveAnswer() (correctAnswer string) {
-	return s.session.CorrectAnswer()
+func (s *SessionState) ResolveAnswer(answer string) (correctAnswer string) {
+	return s.session.SubmitAnswer(answer)
 }
 
-// Advance fetches next question if available or sets session to finished otherwise.
+// ResolveSides submits answers for each side to a session. Results
+// are used to rate the next Advance call.
+func (s *SessionState) ResolveSides(answers []string) SidesResult {
+	s.results = s.session.SubmitSides(answers)
+	return SidesResult{s.session.CorrectSides(), s.results}
+}
+
+// Advance fetches next question if available or sets session to
+// finished otherwise. Score of answers resolved via ResolveSides is
+// derived from correctness of each side.
 func (s *SessionState) Advance(score leaf.ReviewScore) {
-	rating := s.rater.Rate(s.Question, score) // increment misses in auto rater
+	var rating float64
+	if sr, ok := s.rater.(leaf.SidesRater); ok && s.results != nil {
+		score = leaf.SidesScore(s.results)
+		rating = sr.RateSides(s.session.CardID(), s.results)
+	} else {
+		rating = s.rater.Rate(s.session.CardID(), score) // increment misses in auto rater
+	}
+	s.results = nil
 
 	if score == leaf.ReviewScoreAgain {
 		s.session.Again() // nolint: errcheck
 	} else {
-		s.session.Rate(rating) // nolint: errcheck
+		s.session.Rate(rating, score) // nolint: errcheck
 		s.Left = s.session.Left()
 	}
 
 	s.Question = s.session.Next()
 	s.AnswerLen = len([]rune(s.session.CorrectAnswer()))
+	s.SideLengths = sideLengths(s.session.CorrectSides())
+}
+
+func sideLengths(sides []string) []int {
+	result := make([]int, len(sides))
+	for idx, side := range sides {
+		result[idx] = len([]rune(side))
+	}
+
+	return result
 }
@@ -5,19 +5,20 @@ import (
 
 	"github.com/ap4y/leaf"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSessionState(t *testing.T) {
 	cards := []leaf.CardWithStats{
-		{Card: leaf.Card{Question: "foo", Sides: []string{"bar"}}, Stats: leaf.NewStats(leaf.SRSSupermemo2Plus)},
-		{Card: leaf.Card{Question: "bar", Sides: []string{"baz"}}, Stats: leaf.NewStats(leaf.SRSSupermemo2Plus)},
+		{Card: leaf.Card{Question: "foo", Sides: []string{"bar"}}, Stats: &leaf.Stats{SRSAlgorithm: leaf.NewSupermemo2Plus(leaf.SystemClock, leaf.DefaultSupermemo2PlusParams())}},
+		{Card: leaf.Card{Question: "bar", Sides: []string{"baz"}}, Stats: &leaf.Stats{SRSAlgorithm: leaf.NewSupermemo2Plus(leaf.SystemClock, leaf.DefaultSupermemo2PlusParams())}},
 	}
 
 	stats := make(map[string]*leaf.Stats)
 	s := leaf.NewReviewSession(cards, leaf.RatingTypeAuto, func(card *leaf.CardWithStats) error {
 		stats[card.Question] = card.Stats
 		return nil
-	})
+	}, nil, leaf.SystemClock)
 
 	state := NewSessionState(s)
 	t.Run("state", func(t *testing.T) {
@@ -28,7 +29,7 @@ func TestSessionState(t *testing.T) {
 	})
 
 	t.Run("ResolveAnswer", func(t *testing.T) {
-		answer := state.ResolveAnswer()
+		answer := state.ResolveAnswer("baz")
 		assert.Equal(t, "bar", answer)
 		assert.Equal(t, 2, state.Left)
 	})
@@ -46,16 +47,53 @@ func TestSessionState(t *testing.T) {
 	})
 }
 
+func TestSessionStateSides(t *testing.T) {
+	cards := []leaf.CardWithStats{
+		{Card: leaf.Card{Question: "foo", Sides: []string{"bar", "bazz"}}, Stats: &leaf.Stats{SRSAlgorithm: leaf.NewSupermemo2Plus(leaf.SystemClock, leaf.DefaultSupermemo2PlusParams())}},
+		{Card: leaf.Card{Question: "bar", Sides: []string{"baz"}}, Stats: &leaf.Stats{SRSAlgorithm: leaf.NewSupermemo2Plus(leaf.SystemClock, leaf.DefaultSupermemo2PlusParams())}},
+	}
+
+	reviews := make([]*leaf.Review, 0)
+	s := leaf.NewReviewSession(cards, leaf.RatingTypeAuto, func(card *leaf.CardWithStats) error {
+		return nil
+	}, func(review *leaf.Review) error {
+		reviews = append(reviews, review)
+		return nil
+	}, leaf.SystemClock)
+
+	state := NewSessionState(s)
+	assert.Equal(t, []int{3, 4}, state.SideLengths)
+
+	res := state.ResolveSides([]string{"bar", "baz"})
+	assert.Equal(t, SidesResult{[]string{"bar", "bazz"}, []bool{true, false}}, res)
+
+	state.Advance(leaf.ReviewScoreEasy)
+	assert.Equal(t, 1, state.Left)
+	assert.Equal(t, "bar", state.Question)
+	assert.Equal(t, []int{3}, state.SideLengths)
+
+	require.Len(t, reviews, 1)
+	assert.Equal(t, leaf.ReviewScoreHard, reviews[0].Score)
+	assert.InDelta(t, 0.69, reviews[0].Rating, 0.01)
+	assert.Equal(t, "bar baz", reviews[0].Answer)
+
+	state.ResolveSides([]string{"qux"})
+	state.Advance(leaf.ReviewScoreEasy)
+	assert.Equal(t, 1, state.Left)
+	require.Len(t, reviews, 2)
+	assert.Equal(t, leaf.ReviewScoreAgain, reviews[1].Score)
+}
+
 func TestSessionStateUnicode(t *testing.T) {
 	cards := []leaf.CardWithStats{
-		{Card: leaf.Card{Question: "hello", Sides: []string{"おはよう"}}, Stats: leaf.NewStats(leaf.SRSSupermemo2Plus)},
+		{Card: leaf.Card{Question: "hello", Sides: []string{"おはよう"}}, Stats: &leaf.Stats{SRSAlgorithm: leaf.NewSupermemo2Plus(leaf.SystemClock, leaf.DefaultSupermemo2PlusParams())}},
 	}
 
 	stats := make(map[string]*leaf.Stats)
 	s := leaf.NewReviewSession(cards, leaf.RatingTypeAuto, func(card *leaf.CardWithStats) error {
 		stats[card.Question] = card.Stats
 		return nil
-	})
+	}, nil, leaf.SystemClock)
 
 	state := NewSessionState(s)
 	t.Run("state", func(t *testing.T) {
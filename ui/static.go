@@ -275,72 +275,81 @@ uov3H1dKHu5nxh3siMzwAg/xq2JIezGMJVxITg/zKjeMUr4S3ClkcdubuILwRfi1AcoI0qUCEIgIin6O
 	"/main.js": {
 		name:    "main.js",
 		local:   "ui/static/main.js",
-		size:    2810,
-		modtime: 1567739819,
+		size:    3079,
+		modtime: 1792272204,
 		compressed: `
-H4sIAAAAAAAC/6xWTY/jNgy9+1ew3h5sIPX0PIELTL+AFkW32CnQY6LazFo7jqWKyqRG4P9e6Mu2Yicz
-h94Chnx6fHyizI9SKA0/YvXyGycNByWOkBYPNVYvu5aTLr5Quk182id85Xh+RiIuujFX2eiOXDgqeNZM
-UwRMJjJDTqqWEcGTlHBJACrRkVanSguV5TYCoBtORR0YltDheSSc5dspZ2eSCEqoRXU6YqeLz6h/atH8
-/L7/pc5Sm5AuawomJXb1Dw1v6yw6r0BXn2+TqYjGthybsc2Yjk27R8cmpMuaJZ3xxFU+KpqL4xTNKuIV
-ZRcKSbSv+NTRGRWUkOVQfufJRP/dwWD1K+sqnAgw6rsKqBIKDZqbo58u0JR2Zlz7s2KMzNb6E1fPnFD8
-L5c7RGKOObdH4FKuhhD6uh5DTGEaBcBgxqGwq3Ey7pl3tTgXopNCmgHiKO9l3hc14mz8TEHhwc/WydUw
-aqAMYK2omDaHm/B2fkzDSQvVFwplyyo0nsTsMmwgNeBPUqYbSB9Co/wA2VcGI3+DjOlKn1SQNxmrTbHx
-pdL0F9dNln7wdl5BtBfEVXh6If0bwyrNQ+eALeFVvTki2OIKIi4OY/DumzqZLxJ/4Un3LRY1J9my3lyZ
-U9uuWeA6L+1Eh+nyxt7KW26wImypufsPqKtmpvu8j3n/pnYDFVM1fUJW96E1M5ApCmVZwrf5OLk1m8gT
-Nd4jYFBh2MDeLY1H+PpiQsN+A/sP4ffK1nyHODdkXMj9ThHjC2h5l5b+zd20vm0Woi5UH11r/41v9F0N
-bVUsoXP6TMj/Ucn3GtIpvvKG3VBx+j88Ywu/zvS5Um+n8J8Tks4k080GhDQry2BchiClW24KJ2QvroWO
-C2c7SyEV4iVY21ea4Bdyz5zNZC0qne1/ZrzFGrQAC2qGMhVo/Fdn+ehrDxiMaZuJrqXn7fPCA+nanH9Y
-zCqXBlqt3luFH6KL5lCWNn0LR+mHyXlhjx5RN6J+hPSPj89/pm5Zzk65eubvNupz562uvtx3QXxFeovh
-xkf/FnX/CL8+f/y9IK1495kf+uzivyuGPGpkSBJvICal/wZ6ktJYYooX4YXeJv8FAAD//1mSjsL6CgAA
+H4sIAAAAAAACA61WW2/TMBR+768w2YQSqaQ8rypoDJCGEEMriMfVxO7iLY2D7a5EVf87x7ckTtJuD7w5
+J+c718/nmG0qLhT6SLPHr0wqtBZ8g6J0RkBwV4AkfZDRfMKs2i19YnS3pFIyXja6wkjvpBUHgKXCSgaG
+pZZ0LE+yAkuJLqsK7ScIZbyUSmwzxUWcGAlCKmcyJT7CBSrprgk4Tuatzp1WkqBBeLbd0FKl91R9Kqg+
+fqivSRwZhWiISXFV0ZJc5awgceAvpRYPmBYkm7RsNE2aYThG7VQ4RiEaYobhNB5H4xFBX2xMQa+CuAJt
++JK8eKKXpdxRAdgYm9MUSUaoTNDinUH6+AL1nu5RH5g84TKjbYBY1mWGZMYFBQeuz677SLZqO8yUcxza
+iA3WeRz12VpxJ6t7CIrd6BxvkVXpNcnn1W9TGELbKvCr2yVAm7bE3rGS8F3Ky4pXusFUlz/pFsT2Puc7
+zXfpu3hwvbflyrHMAeiMFTzDSjvX4nnXDZiCW1VDjFWBoZDaYbw/TFGkjcMFjOA484myNYpfaRvJM8Ho
+rNRW+PJOGrQGa94KJX8xlcfRmaP7iEVzgSzChefV3+ioAOPMI1pI2sNrF54WPRMh2LfBsa/NpDto3ECQ
+qi5oSpgEU7W+UtuiGKNAXy8qeUmj4Y0+pjeccKmfYl32r6nK8k7du3l089fYKcqwIPKWYlL71HRDWila
+LBbobdJ0bowm1VbmjiNIW0XAlJUdKhfofK9FhxWIzvx5ZKq+oDhHyjgo9wuLGF5AE/fChH90No1Pm0FR
+B1VvWGv+hjf6ZA0NKiyhZXqnkP+xki8lpK34yI47UsX2v19zA7526tOrHqyRP1sK+7LCKp8iXumRpW3s
+D76UdrjBumksu+Ia0yGwM7MAkPJHT22H1MIHadeg0cQFFSpefcasoAQpjoxR3ZQWoOhf2OcNr51BT0yT
+THAtXdxOz29Lm2b34dFBDgk0il6ZCs+Ci2atDGn6nB2hZi3z/BzdUJVzcoGi7zfLH5Edlh0vYztfMyty
+i9+xpztr3OMhXOwQhdBUMz/dH4TSDa6Mvl58K31YnO9pmXFCf95eX3F4SZawRY2KbkcLfOCsjKPXUW8T
+9bN24b8/35sAmo66XXUaZLMdj8j+S4KOjD5TTlLDIaJj7Zg66W9O6gv0ZXnzDW6cYOU9W9cwV+wj6pAE
+XYNY3G2BJ4p7EMKG1/xv5al/jswn/wDE11ZCBwwAAA==
 `,
 	},
 
 	"/rater.js": {
 		name:    "rater.js",
 		local:   "ui/static/rater.js",
-		size:    3593,
-		modtime: 1568847482,
+		size:    5234,
+		modtime: 1792272204,
 		compressed: `
-H4sIAAAAAAAC/8RWzY7bNhC+6ymmbFFIRVfen5y6loAFsmgCpAG67nGBLE2NbXZpUiEpO8bCr9Aeit76
-dHmSQhQli16vLSQFerJJzffNcIbDb5iSxgKtrLqjFgvI4CEaz5ReAi8ywmVZ2bN6SYAJakywlUcAY7fe
-GRPHNVOsMu4fU8tSoMWMqNmMjHoQuykxI6aaLrnt2Jvl2bSyVkkCKyoqzMjnP/8gMMqj8aj2m0fRuHQe
-NZpK7MB+6XyYkkpnQ6VZoz4zllok+fdyasrxqP4amjGlNTJ71pjvGY5HZR49XEcRfiqVto0/uPFJ0/AU
-AbhE6opZpePE7QDYBTepYUojZHB+vdv7gAIyKBSrlihtyjRSi7cC61VMCr4iSWidcilRv/ntl3eQ7aq1
-Z/OxQr2ZoEAXBPm2V6okVbLJLWSAkOU+QgBMS40rlPY1zmglbOw9t7xKThwu3h3GW2zrn20UAczRAvro
-27NrtJWWXXCdrUELHSejQkwpewwS1rmEDFqDHXyh1r9WaCxXMn6CplwfBMq5XcA25DmQkuA2JEFWiSs5
-OZHTvXvyAoXjaFqruezZiSq19XaL1NiNwHTNC7uoG/K7p0v4ITzqli0e+gjXKHUEpL/r2rApaJe8O9ck
-ccPW5qsJtTKo3w4Mt/F43UM3jJM6s8fwYQX6BD61N87gGMV+DYJ8l9Ra1LJuE/c5NaXgNh7dm1GS/q64
-jMn9venyPYO4O3a6pJYtYolruMP57acy9mRJknT90jtmWPvP//xFrg8YNcVkSqj6UGSuEWVnGJz55eu4
-95Rc+AYEFAaHRPb3kMg0FgPiaggOhuVfuK27btsoagpiUMx62jL85a6BR97jnkxpXHFchzqlqeVyflCo
-aLGikiHZkyAvNpOFWkNzcAKUMTTmETcZMSVlWCtYTVbwVePZeXEOAMaNbLVE5wH8guQ3c8rleNRYHYRc
-BJBLkr+hujiKuAwQVyT/WanjiKsA8Yrkt9Rs+ojxqOCrndg+072JL+gJ3fs6jetujW/ujocWxW0tV++4
-sShRx+QRN4VaS/JjKGx1a3/TcZdUo7TvVYFJK02yEsKzA5g1t2wBcd0PtU3LAsCoQSATV/2fut09gesU
-rVPPFviaz7m9GIA8Pwy9HAC9OAy9GgC9PAx9NQB61UG3UWDXJNZ9SE7Iab9z/4MZpT+ZvOT1RoiY+AEz
-SWdK31K2iL1M5+3lcXrw/K4xwdnj/k07FuCzEN9Xyynq+j4afCttjKmleo5ewpNdThP3J/nfh6yP/s/J
-2ar/Xn/JaOXf0sTr0oobPuWC203NsOBF0enmy8OFf9kPc7iVQDJ0Hho8kAQnb0eaUwL6FcnoH+SLs9HL
-aK3W/wYAAP//fRdB/QkOAAA=
+H4sIAAAAAAACA7VYzW7bRhC+6ym2WyMgC5uS7JxsiYbRGE2AJECj3hwjosiVvDVFMrtLy4KgV2gPRW59
+ujxJZ39I7srUT5z0YpvkfLMzs9/8Oc4zLlBUivxDJEiChmjcGUxzNkc0GWKaFaU4kY8YxWnEufMq7CA0
+SOhDI8pdMa5EQEg9NWJYHTjN45Krv+J8XqREkCHOp1PcVXq7oFj9obFiWcBnXk7mVNSH6MeTSSlEnmH0
+EKUlCH39+y+MQMmgK60MO51BoY5mhJdpAzaP6gxeRJmSiTK+IOyECwgGDl9kE14MuvKrKxbnjJFYnGjx
+DcFBt4AfZaokOU3IiT6qCY7zMhx0yzTsjC86HfJY5ExoKXRl7oShFZwdy3tiZSxy5vnqDULijvKAgy0E
+rq130bz7RFJ4k0B85yQTQcwI6LlOiXzyMAQW+650QLOMsNd/vHsLuJoMGzKfS8KWI5ISZQT+2WKCH4B5
+6jIAD9aExkKESFAw8gDnviLTCPz1zMmV3jwbKZzXOGMk1vLXugM/ZkQgYqyvfGdElCyrjXNk9a20isrI
+8+DPnGYeRjoKNUx920RdMRYtgynL5157IK7StIoFR5rdvh/Mo8Izfmr+QkjUH4EiqfrUnM7h9DoQcZSm
+kyi+d265jhPEtxJo4Hf54veScEHzzFsZ9z+lJJuJu2PllnngaO1qbblVJwN8hxhY0RzvocVGbjxHhZMf
+GwoAq8AqIVDl19AE23H2xQvnOdC/UYh66NKVPEc3TtRuL6wzzN0O92QCr5JKPzlWm3OMkTY7EPI+HUOl
+ePSBIvU7hMZWzTxawXc0HA6V4VUJPUdjnYHq83q8xq21dGyU+tXhmvzSWNvap5w2XA4gwa+j+M7z1Iva
+1jrDNa25WKYkWNAEAgw95Gh1in6p3L4ByO06vhubxHbjtBHM5ljoDp7vkPyDokRNcc1tfowM5Rp20yny
+zEu/NlRfn9QzkjCjzFVRVyed/sZgm3ElJ+yNTuk9hAAnVLLbXNKGj2Ry7cK7SWgrMHZeKYFdKjbT0Emb
+IhLQWDJZ7NXngBcplJ7uR971DUE+fmwIDdGs3QbyCmBDRhboA5ldPxaeUeb7TagtN93s/frvP/iiRUjT
+J87TXDqFZ4yQrBZ0fN5eTjYaYt9cHrQOTg6x7MshljGSHGCXVtBqVq/ilOHVPkIay38QfaB1/GqSZVgd
+EUB/ZksvlkkdG+ltYbLwl/o2oQ5ZsdseORepL1him4A6Yaps4/mcVKYBsA+QXlW5gJa6n5vCLitkfzPf
+v7VDNXbW1fiy4ln95lxXnpve7Y568Zz2ptTabUJ5+LRBGBdUZbW6xiX0jZQiFXk5pULoz5GOdQiXhY5W
+Ut160E1p3Ri0Py04eTOA+tKKamkn0nFwXRONk3RqbRSHT+ASuGOutpYTmCspWbjbCYsEzWbWetJ00Sh5
+iLKY4I1VwiwNI8hCpDMZ+mgcE87vyRLEiggwsE3Yu44+pVpu9PpRKeo58D4Or2YRzQZdLdUK6TuQUxy+
+jliyE3HqIM5w+Fue70acOYiXOLyO+NJGmIWrWpqerCMjc6F71pHvWz1q1pgUr/VESXItt4i3lAsC8h4G
+P5J8keFjd9+QReGnWncRMQC9zxPiVwN9Vqap0Q5DxYJCM0OeLFNSZtUkWARNA4/U7Z9bmeLsHfXMXo8N
+FfAVnVHRPwDZa4eeHgDtt0PPDoCetkNfHgA9q6Frp/SZwNoD3tYCaGfuD1gd7YVx26lqpDX/KGhm2mo3
+c0bZp1yLUxrfbzJtl4FPTHxfzicwIwAfOXkDmUACETFYOvWE6Dcx9TdWwx+z+2K8f9O9wfj2f9lIP5s/
+9q6edul/ztpoyrJvJo8HyumEwly7lBruaJLUM+X2yck0iXYd6ikl+IClpNlFvnFud4JQjW7b5kx9lF5m
+q39rDCYsxD609GoI/Y6g2Q4/O2pW5OWA8B8v72lrchQAAA==
 `,
 	},
 
 	"/review_session.js": {
 		name:    "review_session.js",
 		local:   "ui/static/review_session.js",
-		size:    2027,
-		modtime: 1567985489,
+		size:    2048,
+		modtime: 1792272204,
 		compressed: `
-H4sIAAAAAAAC/5xVwW7jNhC96yumag8y4MoNglxiS0XQFuhhF9iN9x4z0tjihiYVcmSvYejfF5RISXSc
-HPZim8SbNzOPb8Z8XytNcIaHhtQjI9RzWKPYdj+hha1We4jThbbn9LuJl1FUKGkICPe1YISQwSZaVchK
-1HkEsKpu83+xeLmHlamZBF5mcYnFS5yvFvYiXy2q2x54l3/RaqfRmCm4dnfTgLs8Wi18jmi1Z7zHGjSG
-KxlDIZgxwxkKJYlxiTruM9106NcGDU3hxEmgzVPd2ASWNo82yyjCH50sJW5ZI6iHwyMeOB7XLsU5AuiU
-0E1BSiez7gaAKm7SJxSQQamKZo+S0kIjI/xPoD0lcckP8WwZoFMuJer/v33+BNkg7TIaMWZ4lAwkHsdH
-SqZMAypVct087zlBBkyao43LO5zPWTFZCuwpwti5i5hN8zPvD5d/8EuQf0D9Qn42OnDID9DaGnZIgE49
-r7NGarQcBBywBu27FS+J/bh8lPS1QX1ao8DuzeLfO2fOAvXtVcDmTJW475DTOy7zsOm7apSlV2ig02iU
-OOBD12NSMCGe2WWlAQYy8KiAiJUHJgt0hnyHKQRdoxqqnMZx0+fmcmcNqRuc9tXUJSNcEyOcNBdeO7Z+
-WZzBz94cSBETcxC4pTloRlzunuhUI7Q201RU5z++hcSiIcsy+MsTAxy5LNUxrbghpU+pbcqb0dujP7XR
-xyYYNk5ohM0f565W+LMrtl24c7tZfsw37JmQz1+7tnpltJupqRC2z9iOYwx/X07//cWgLSdcoxffLc0v
-TKeTldZdpUXFRalRpgLljqquiptRbQ9jdY2y/MeCk/5fwU2mo2wBhcE3YRprwQq8EjcfMIIZ6gCeqh/0
-DmwqdfzqBEwCl4wGZOYkCwg2i+4XiimURt+LbfrS5GOfV+y/ZcKgN5Y3tNtq1rTsyDhdm9yJHYcmHtE0
-gpLJinujWVfgqUa17QuH36whZLN/Rh3PAmtfH/Ok73dUsY3a6GcAAAD//50A0bzrBwAA
+H4sIAAAAAAACA51VwY7TMBC95yuGwCGVSpYV2su2KVoBEgeQYMt9602mjdnUCbbTUlX9d8aOncSluwcu
+SWw9v5l5fjPh26aWGo5w1+r6nmmUU1hitbafcIK1rLcQp1fSrNNfKp5FUV4LpUHjtqloFzJYRfMSWYFy
+EQHMy/eLT5g/3cJcNUwAL7K4oHW8mF+ZDXoRwgJvFt9lvZGo1BjcuL3xgZtFRE8XI5pvGe+winC8FjHk
+FVOqXwOlqAmDMu4iXVv07xaVHsM11xWaOOW1CWBoF9GKSsQ/VpYC16ytdAeHe9xx3C9diCMRWyVkm+ta
+JhO7A6BLrtIHrEiXos7bLQqd5hJJqc8VmlUSF3wXT2YBOuWCsv3y89tXOuelpUR6jOovJQOB++GSkjFT
+j0prsWwft1wTnAm1N+cWFudjlkwUFXYU4dmpOzEZx2feHy5+75cgfo/6j/hscGAfH+BkctigBnTqeZ0l
+6laKXsAeq9DcW/6UmMf5paRkAXkg7dDeWfzaOnMSqG+2AjZnqsS9Q07vuMzDxvcqURReoZ6OvF1XO7yz
+NSY5q6pHdp5pgCFujwqIWLFjIkdnyGeYQtAlqj7L8TmuuthcbIwhZYvjutqmoItaanqMigu3HVs3LI7g
+e28KutasmkKFaz0FmisU4kEfGqRpk4WiOv/xNSQGDVmWwTtPDLDnoqj3KZ2guzykpihvRm+PbnWKXjZB
+P3FCI6zeHG2u8NYme7py69Nq9jJfP2dCPr/tyuqUka6nxkKYOmPTjjF8OO/+27NGm424Bi8+m5ofmE4n
+I63bSvOSVwV5Ia1QbHRps7ge1PYw1jTkl48GnHR/BdeZjvJEnarwn2MSaaTleOHctMfQlNUW4Km6Rrdg
+Vdb7H07AJHDJYECmDiKHYLLIbqCovJboazFFn5t8qPOC/deMKvLGcteGyvwYaLjtGQ25C13riuxG2dRX
+wQtUg0f7yu4tXdKxXhbSZk3uqNddNfDKuES020f6y00Cv1/u/aQTYZD2FJ2iv02ejM0ACAAA
 `,
 	},
 
@@ -13,7 +13,8 @@ class App {
     this._stats.appendChild(this.statsList.element);
 
     this.reviewSession = new ReviewSession();
-    this.reviewSession.resolveAnswer = () => this._resolveAnswer();
+    this.reviewSession.resolveAnswer = (answer, sides) =>
+      this._resolveAnswer(answer, sides);
     this.reviewSession.advanceSession = async score => {
       const session = await this._advanceSession(score);
       this.reviewSession.session = session;
@@ -94,8 +95,15 @@ class App {
     });
   }
 
-  _resolveAnswer() {
-    return this._request("resolve");
+  _resolveAnswer(answer = "", sides = null) {
+    if (sides) {
+      const query = sides
+        .map(side => `side=${encodeURIComponent(side)}`)
+        .join("&");
+      return this._request(`resolve?${query}`);
+    }
+
+    return this._request(`resolve?answer=${encodeURIComponent(answer)}`);
   }
 
   _advanceSession(score) {
@@ -1,6 +1,8 @@
 const autoRated = `
 <form id="input-form" class="input-form">
-  <input id="input" autofocus autocomplete="off"/>
+  <div id="inputs" class="inputs">
+    <input id="input" autofocus autocomplete="off"/>
+  </div>
   <input type="submit" class="submit-button" value="⏎" />
 </form>
 
@@ -8,6 +10,7 @@ const autoRated = `
   <span id="answer-state">&nbsp</span>
   <span id="correct-answer">&nbsp</span>
 </p>
+<ul id="side-results" class="side-results"></ul>
 `;
 
 export class AutoRater {
@@ -25,21 +28,47 @@ export class AutoRater {
     return this._el;
   }
 
+  get answer() {
+    return this.sides.join(" ");
+  }
+
+  get sides() {
+    return Array.from(this._el.querySelectorAll("#inputs input")).map(
+      input => input.value
+    );
+  }
+
   set onSubmit(callback) {
     this._onSubmit = callback;
   }
 
-  showQuestion({ answer_length }) {
+  showQuestion({ answer_length, side_lengths }) {
     this._el.querySelector("#answer-state").innerHTML = "&nbsp";
     this._el.querySelector("#correct-answer").innerHTML = "&nbsp";
-
-    const input = this._el.querySelector("#input");
-    input.style.width = `${2 * answer_length}ch`;
-    input.value = "";
-    input.focus();
+    this._el.querySelector("#side-results").innerHTML = "";
+
+    const lengths =
+      side_lengths && side_lengths.length > 0 ? side_lengths : [answer_length];
+    const inputs = this._el.querySelector("#inputs");
+    inputs.innerHTML = lengths
+      .map(
+        (_, idx) =>
+          `<input id="${idx === 0 ? "input" : `input-${idx}`}" autocomplete="off"/>`
+      )
+      .join("");
+
+    inputs.querySelectorAll("input").forEach((input, idx) => {
+      input.style.width = `${2 * lengths[idx]}ch`;
+    });
+    inputs.querySelector("input").focus();
   }
 
-  showResult(answer) {
+  showResult({ answer, sides, correct }) {
+    if (correct) {
+      this._showSidesResult(sides, correct);
+      return;
+    }
+
     const userInput = this._el.querySelector("#input").value;
     const answerState = this._el.querySelector("#answer-state");
     const correctAnswer = this._el.querySelector("#correct-answer");
@@ -57,6 +86,29 @@ export class AutoRater {
       this.score = 0;
     }
   }
+
+  _showSidesResult(sides, correct) {
+    const answerState = this._el.querySelector("#answer-state");
+    const allCorrect = correct.every(c => c);
+    answerState.innerHTML = allCorrect ? "✓" : "✕";
+    answerState.style.color = allCorrect ? "green" : "red";
+    this.score = correct.some(c => c) ? 1 : 0;
+
+    if (sides.length === 1) {
+      this._el.querySelector("#correct-answer").innerHTML = allCorrect
+        ? "&nbsp"
+        : sides[0];
+      return;
+    }
+
+    this._el.querySelector("#side-results").innerHTML = sides
+      .map((side, idx) =>
+        correct[idx]
+          ? `<li style="color: green">✓ ${side}</li>`
+          : `<li style="color: red">✕ ${side}</li>`
+      )
+      .join("");
+  }
 }
 
 const selfRated = `
@@ -114,6 +166,14 @@ export class SelfRater {
     return this._el;
   }
 
+  get answer() {
+    return "";
+  }
+
+  get sides() {
+    return [""];
+  }
+
   set onSubmit(callback) {
     this._onSubmit = callback;
   }
@@ -124,9 +184,9 @@ export class SelfRater {
     this._el.querySelector("#advance").style.visibility = "visible";
   }
 
-  showResult(answer) {
+  showResult({ answer, sides }) {
     const correctAnswer = this._el.querySelector("#self-answer");
-    correctAnswer.innerHTML = answer;
+    correctAnswer.innerHTML = sides ? sides.join("<br>") : answer;
 
     this._el.querySelector("#rating").style.visibility = "visible";
     this._el.querySelector("#advance").style.visibility = "hidden";
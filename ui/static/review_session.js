@@ -75,8 +75,8 @@ export default class ReviewSession {
   async _handleRater(rater, score) {
     if (this.isAnswering) {
       this.isAnswering = false;
-      const { answer } = await this._resolveAnswer();
-      rater.showResult(answer);
+      const result = await this._resolveAnswer(rater.answer, rater.sides);
+      rater.showResult(result);
     } else {
       if (typeof score !== "number") return;
       this._advanceSession(score);
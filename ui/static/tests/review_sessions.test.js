@@ -85,6 +85,43 @@ describe("auto rater", () => {
     await new Promise(resolve => window.setTimeout(resolve, 100));
     expect(rating).toEqual(1);
   });
+
+  test("submit multiple sides", async () => {
+    const reviewSession = new ReviewSession();
+    reviewSession.session = { ...session, side_lengths: [3, 3] };
+
+    let sides = null;
+    reviewSession.resolveAnswer = (answer, s) => {
+      sides = s;
+      return {
+        answer: "bar baz",
+        sides: ["bar", "baz"],
+        correct: [true, false]
+      };
+    };
+
+    let rating = null;
+    reviewSession.advanceSession = r => {
+      rating = r;
+    };
+
+    const el = reviewSession.element;
+    expect(el.querySelectorAll("#inputs input").length).toEqual(2);
+    el.querySelector("#input").value = "bar";
+    el.querySelector("#input-1").value = "qux";
+    el.querySelector("#input-form").onsubmit({ preventDefault: () => {} });
+    await new Promise(resolve => window.setTimeout(resolve, 100));
+    expect(sides).toEqual(["bar", "qux"]);
+    expect(el.querySelector("#answer-state").innerHTML).toEqual("✕");
+    expect(el.querySelectorAll("#side-results li").length).toEqual(2);
+    expect(
+      el.querySelector("#side-results li:last-child").innerHTML
+    ).toEqual("✕ baz");
+
+    el.querySelector("#input-form").onsubmit({ preventDefault: () => {} });
+    await new Promise(resolve => window.setTimeout(resolve, 100));
+    expect(rating).toEqual(1);
+  });
 });
 
 describe("self rater", () => {
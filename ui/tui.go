@@ -25,18 +25,20 @@ const (
 	alignRight
 )
 
-// TUI implements terminal UI.
+// TUI implements terminal UI. Each side of a card is answered in a
+// separate field, Tab and Enter switch to the next field.
 type TUI struct {
 	deckName    string
-	userInput   []rune
+	userInputs  [][]rune
+	side        int
 	step        step
-	prevResult  bool
-	prevCorrect string
+	prevResults []bool
+	prevSides   []string
 }
 
 // NewTUI construct a new TUI instance.
 func NewTUI(deckName string) *TUI {
-	return &TUI{deckName: deckName, userInput: make([]rune, 0)}
+	return &TUI{deckName: deckName}
 }
 
 // Render renders current ui state using termbox.
@@ -45,6 +47,7 @@ func (ui *TUI) Render(s *SessionState) error {
 		ui.step = stepFinished
 	}
 
+	ui.resetInputs(s)
 	ui.draw(s)
 
 	for {
@@ -75,11 +78,7 @@ func (ui *TUI) Render(s *SessionState) error {
 						continue
 					}
 				} else {
-					if ui.prevResult {
-						score = leaf.ReviewScoreEasy
-					} else {
-						score = leaf.ReviewScoreAgain
-					}
+					score = leaf.SidesScore(ui.prevResults)
 				}
 
 				s.Advance(score)
@@ -88,18 +87,31 @@ func (ui *TUI) Render(s *SessionState) error {
 				} else {
 					ui.step = stepAnswering
 				}
+				ui.resetInputs(s)
 
 				break
 			}
 
-			if ev.Key == termbox.KeyEnter {
-				ui.prevCorrect = s.ResolveAnswer()
-				ui.prevResult = ui.prevCorrect == string(ui.userInput)
+			input := &ui.userInputs[ui.side]
+			if ev.Key == termbox.KeyTab {
+				ui.side = (ui.side + 1) % len(ui.userInputs)
+			} else if ev.Key == termbox.KeyEnter {
+				if s.RatingType != leaf.RatingTypeSelf && ui.side < len(ui.userInputs)-1 {
+					ui.side++
+					break
+				}
+
+				answers := make([]string, len(ui.userInputs))
+				for idx, input := range ui.userInputs {
+					answers[idx] = string(input)
+				}
+
+				res := s.ResolveSides(answers)
+				ui.prevSides, ui.prevResults = res.Sides, res.Correct
 				ui.step = stepScore
-				ui.userInput = make([]rune, 0)
 			} else if ev.Key == termbox.KeyBackspace || ev.Key == termbox.KeyBackspace2 {
-				if len(ui.userInput) > 0 {
-					ui.userInput = ui.userInput[:len(ui.userInput)-1]
+				if len(*input) > 0 {
+					*input = (*input)[:len(*input)-1]
 				}
 			} else {
 				var ch rune
@@ -109,7 +121,7 @@ func (ui *TUI) Render(s *SessionState) error {
 					ch = ev.Ch
 				}
 
-				ui.userInput = append(ui.userInput, ch)
+				*input = append(*input, ch)
 			}
 		case termbox.EventError:
 			return ev.Err
@@ -133,7 +145,11 @@ func (ui *TUI) draw(s *SessionState) {
 		return
 	}
 
-	write(s.Question, w/2, h/2-4, alignCenter, termbox.ColorYellow|termbox.AttrBold, 0)
+	lines := strings.Split(s.Question, "\n")
+	for idx, line := range lines {
+		y := h/2 - 4 - (len(lines) - 1) + idx
+		write(line, w/2, y, alignCenter, termbox.ColorYellow|termbox.AttrBold, 0)
+	}
 	if s.RatingType == leaf.RatingTypeSelf {
 		ui.drawSelfRater(s)
 	} else {
@@ -143,25 +159,52 @@ func (ui *TUI) draw(s *SessionState) {
 
 func (ui *TUI) drawAutoRater(s *SessionState) {
 	w, h := termbox.Size()
-	write("(type answer below)", w/2, h/2-3, alignCenter, 0, 0)
+	if len(ui.userInputs) > 1 {
+		write("(type answers below, Tab switches sides)", w/2, h/2-3, alignCenter, 0, 0)
+	} else {
+		write("(type answer below)", w/2, h/2-3, alignCenter, 0, 0)
+	}
 
-	x := (w / 2) - (s.AnswerLen / 2)
-	inputBox := []rune{}
-	for i := 0; i < s.AnswerLen; i++ {
-		inputBox = append(inputBox, '_')
+	width := 0
+	for _, l := range s.SideLengths {
+		if l > width {
+			width = l
+		}
 	}
-	write(string(inputBox)+string('⏎'), x, h/2, 0, termbox.ColorWhite, 0)
 
-	switch ui.step {
-	case stepAnswering:
-		input := strings.Replace(string(ui.userInput), " ", "␣", -1)
-		write(input, x, h/2, 0, termbox.ColorGreen, 0)
-	case stepScore:
-		if ui.prevResult {
-			write("✓", w/2, (h/2)+2, alignCenter, termbox.ColorGreen|termbox.AttrBold, 0)
-		} else {
-			write("✕", w/2, (h/2)+2, alignCenter, termbox.ColorRed|termbox.AttrBold, 0)
-			write(ui.prevCorrect, w/2, (h/2)+3, alignCenter, termbox.ColorWhite, 0)
+	x := (w / 2) - (width / 2)
+	for idx, input := range ui.userInputs {
+		y := h/2 + idx
+		inputBox := []rune{}
+		for i := 0; idx < len(s.SideLengths) && i < s.SideLengths[idx]; i++ {
+			inputBox = append(inputBox, '_')
+		}
+
+		fg := termbox.ColorWhite
+		if ui.step == stepAnswering && idx == ui.side {
+			fg |= termbox.AttrBold
+		}
+		write(string(inputBox)+string('⏎'), x, y, 0, fg, 0)
+		write(strings.Replace(string(input), " ", "␣", -1), x, y, 0, termbox.ColorGreen, 0)
+
+		if ui.step == stepScore && idx < len(ui.prevResults) {
+			if ui.prevResults[idx] {
+				write("✓", x+width+2, y, 0, termbox.ColorGreen|termbox.AttrBold, 0)
+			} else {
+				write("✕", x+width+2, y, 0, termbox.ColorRed|termbox.AttrBold, 0)
+			}
+		}
+	}
+
+	if ui.step != stepScore {
+		return
+	}
+
+	y := h/2 + len(ui.userInputs) + 1
+	for idx, correct := range ui.prevResults {
+		if !correct {
+			write(ui.prevSides[idx], w/2, y, alignCenter, termbox.ColorWhite, 0)
+			y++
 		}
 	}
 }
@@ -176,18 +219,31 @@ func (ui *TUI) drawSelfRater(s *SessionState) {
 	case stepAnswering:
 		write(" Show Answer: Enter ", x-9, h/2, 0, termbox.ColorMagenta, termbox.ColorWhite)
 	case stepScore:
-		write(ui.prevCorrect, w/2, h/2, alignCenter, termbox.ColorGreen, 0)
+		for idx, side := range ui.prevSides {
+			write(side, w/2, h/2+idx, alignCenter, termbox.ColorGreen, 0)
+		}
+
 		scores := []string{" Again: 1 ", " Hard: 2 ", " Good: 3 ", " Easy: 4 "}
 		for idx, score := range scores {
 			scoreX := (w / 2) - 16
 			for _, prev := range scores[0:idx] {
 				scoreX += len(prev) + 1
 			}
-			write(score, scoreX, h/2+2, alignCenter, termbox.ColorMagenta, termbox.ColorWhite)
+			write(score, scoreX, h/2+len(ui.prevSides)+1, alignCenter, termbox.ColorMagenta, termbox.ColorWhite)
 		}
 	}
 }
 
+func (ui *TUI) resetInputs(s *SessionState) {
+	sides := len(s.SideLengths)
+	if sides == 0 || s.RatingType == leaf.RatingTypeSelf {
+		sides = 1
+	}
+
+	ui.userInputs = make([][]rune, sides)
+	ui.side = 0
+}
+
 func write(text string, x, y int, align align, fg, bg termbox.Attribute) {
 	var xOffset int
 	switch align {